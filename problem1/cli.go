@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+const cliUsage = `usage: problem1 <command> [flags] [targets...]
+
+commands:
+  order    print the execution order
+  levels   print tasks grouped into parallel levels
+  dot      print the dependency graph in Graphviz DOT format
+  run      run the tasks' commands in dependency order
+`
+
+func runCLI(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, cliUsage)
+		return 2
+	}
+
+	cmd := args[0]
+	switch cmd {
+	case "order", "levels", "dot", "run":
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n%s", cmd, cliUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	file := fs.String("f", "tasks.ini", "task file to load")
+	workers := fs.Int("j", 1, "number of tasks to run in parallel (run only)")
+	keepGoing := fs.Bool("k", false, "keep running unaffected tasks after a failure (run only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	tf, err := LoadTaskFile(*file)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	graph := tf.Graph()
+	if targets := fs.Args(); len(targets) > 0 {
+		graph, err = Subgraph(graph, targets, nil)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	switch cmd {
+	case "order":
+		order, err := ResolveOrder(graph)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for _, task := range order {
+			fmt.Fprintln(stdout, task)
+		}
+	case "levels":
+		levels, err := ResolveLevels(graph)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		for i, level := range levels {
+			fmt.Fprintf(stdout, "%d: %s\n", i, strings.Join(level, " "))
+		}
+	case "dot":
+		opts := ExportOptions{}
+		if len(fs.Args()) > 0 {
+			opts.Subgraph = graph
+		}
+		if cycleErr := FindCycles(tf.Graph()); cycleErr != nil {
+			opts.Cycle = cycleErr.Path
+		}
+		fmt.Fprint(stdout, ExportDOT(tf.Graph(), opts))
+	case "run":
+		policy := FailFast
+		if *keepGoing {
+			policy = ContinueOnError
+		}
+		return runTasks(ctx, tf, graph, ExecOptions{Workers: *workers, Policy: policy}, stdout, stderr)
+	}
+	return 0
+}
+
+func runTasks(ctx context.Context, tf *TaskFile, graph map[string][]string, opts ExecOptions, stdout, stderr io.Writer) int {
+	var mu sync.Mutex
+	run := func(ctx context.Context, name string) error {
+		var out bytes.Buffer
+		var err error
+		for _, command := range tf.Tasks[name].Commands {
+			c := exec.CommandContext(ctx, "sh", "-c", command)
+			c.Stdout = &out
+			c.Stderr = &out
+			if err = c.Run(); err != nil {
+				err = fmt.Errorf("%q: %w", command, err)
+				break
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(stdout, "==> %s\n", name)
+		stdout.Write(out.Bytes())
+		return err
+	}
+
+	report, err := Execute(ctx, graph, run, opts)
+	if report == nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	order, _ := ResolveOrder(graph)
+	for _, task := range order {
+		r := report.Results[task]
+		switch r.Status {
+		case StatusFailed:
+			fmt.Fprintf(stderr, "%s: %s: %v\n", task, r.Status, r.Err)
+		case StatusSkipped, StatusCancelled:
+			if r.SkippedBecauseOf != "" {
+				fmt.Fprintf(stderr, "%s: %s because %s failed\n", task, r.Status, r.SkippedBecauseOf)
+			} else {
+				fmt.Fprintf(stderr, "%s: %s\n", task, r.Status)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "build failed")
+		return 1
+	}
+	return 0
+}
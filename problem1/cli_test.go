@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTaskFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tasks.ini")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write task file: %v", err)
+	}
+	return path
+}
+
+func TestRunCLI(t *testing.T) {
+	path := writeTaskFile(t, sampleTaskFile)
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+		wantOut  string
+		wantErr  string
+	}{
+		{
+			name:    "order",
+			args:    []string{"order", "-f", path},
+			wantOut: "download_deps\ncompile\nrun_tests\ndeploy\n",
+		},
+		{
+			name:    "order for a target",
+			args:    []string{"order", "-f", path, "compile"},
+			wantOut: "download_deps\ncompile\n",
+		},
+		{
+			name:    "levels",
+			args:    []string{"levels", "-f", path},
+			wantOut: "0: download_deps\n1: compile\n2: run_tests\n3: deploy\n",
+		},
+		{
+			name:     "unknown command",
+			args:     []string{"publish", "-f", path},
+			wantCode: 2,
+		},
+		{
+			name:     "unknown command without a task file",
+			args:     []string{"bogus", "-f", filepath.Join(t.TempDir(), "nope.ini")},
+			wantCode: 2,
+			wantErr:  "unknown command",
+		},
+		{
+			name:     "missing file",
+			args:     []string{"order", "-f", filepath.Join(t.TempDir(), "nope.ini")},
+			wantCode: 1,
+		},
+		{
+			name:     "no command",
+			args:     nil,
+			wantCode: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := runCLI(context.Background(), tt.args, &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Fatalf("runCLI() code = %d, want %d (stderr: %s)", code, tt.wantCode, stderr.String())
+			}
+			if tt.wantOut != "" && stdout.String() != tt.wantOut {
+				t.Errorf("runCLI() stdout = %q, want %q", stdout.String(), tt.wantOut)
+			}
+			if tt.wantErr != "" && !strings.Contains(stderr.String(), tt.wantErr) {
+				t.Errorf("runCLI() stderr = %q, want it to contain %q", stderr.String(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunCLIDot(t *testing.T) {
+	path := writeTaskFile(t, "[a]\ndeps = b\n[b]\ndeps = a\n[c]\n")
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLI(context.Background(), []string{"dot", "-f", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("runCLI() code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+	if !contains(stdout.String(), `"b" -> "a" [color="#d62728", penwidth=2];`) {
+		t.Errorf("runCLI() dot output does not highlight the cycle:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if code := runCLI(context.Background(), []string{"order", "-f", path}, &stdout, &stderr); code != 1 {
+		t.Errorf("runCLI() order on cyclic file code = %d, want 1", code)
+	}
+	if !contains(stderr.String(), "cycle detected: a -> b -> a") {
+		t.Errorf("runCLI() stderr = %q, want the cycle", stderr.String())
+	}
+}
+
+func TestRunCLIRun(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	path := writeTaskFile(t, `
+[fetch]
+run = echo fetch >> `+log+`
+
+[build]
+deps = fetch
+run = echo build >> `+log+`
+run = echo built
+
+[lint]
+run = exit 3
+
+[deploy]
+deps = build, lint
+run = echo deploy >> `+log+`
+`)
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI(context.Background(), []string{"run", "-f", path, "-j", "2", "-k"}, &stdout, &stderr)
+	if code != 1 {
+		t.Fatalf("runCLI() code = %d, want 1", code)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if string(data) != "fetch\nbuild\n" {
+		t.Errorf("runCLI() ran %q, want fetch then build and no deploy", string(data))
+	}
+	if !contains(stdout.String(), "==> build\nbuilt\n") {
+		t.Errorf("runCLI() stdout = %q, want build output", stdout.String())
+	}
+	for _, msg := range []string{"lint: failed", "deploy: skipped because lint failed", "build failed"} {
+		if !contains(stderr.String(), msg) {
+			t.Errorf("runCLI() stderr = %q, want %q", stderr.String(), msg)
+		}
+	}
+
+	stdout.Reset()
+	stderr.Reset()
+	if code := runCLI(context.Background(), []string{"run", "-f", path, "build"}, &stdout, &stderr); code != 0 {
+		t.Errorf("runCLI() run build code = %d, want 0 (stderr: %s)", code, stderr.String())
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CycleError struct {
+	Path       []string
+	Cycles     [][]string
+	Components [][]string
+}
+
+func (e *CycleError) Error() string {
+	cycles := make([]string, len(e.Cycles))
+	for i, c := range e.Cycles {
+		cycles[i] = strings.Join(c, " -> ")
+	}
+	return fmt.Sprintf("cycle detected: %v", strings.Join(cycles, "; "))
+}
+
+type MissingDependencyError struct {
+	Task       string
+	Dependency string
+}
+
+func (e *MissingDependencyError) Error() string {
+	return fmt.Sprintf("could not find task %v in the given tasks (required by %v)", e.Dependency, e.Task)
+}
+
+func FindCycles(tasks map[string][]string) *CycleError {
+	g, _ := internGraph(tasks)
+	return g.cycles()
+}
+
+func dependsOn(tasks map[string][]string, task, dep string) bool {
+	for _, d := range tasks[task] {
+		if d == dep {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveOrderCycleError(t *testing.T) {
+	tests := []struct {
+		name           string
+		tasks          map[string][]string
+		wantPath       []string
+		wantComponents [][]string
+		wantMsg        string
+	}{
+		{
+			name: "direct cycle",
+			tasks: map[string][]string{
+				"task1": {"task2"},
+				"task2": {"task1"},
+			},
+			wantPath:       []string{"task1", "task2", "task1"},
+			wantComponents: [][]string{{"task1", "task2"}},
+			wantMsg:        "cycle detected: task1 -> task2 -> task1",
+		},
+		{
+			name: "indirect cycle",
+			tasks: map[string][]string{
+				"c": {"a"},
+				"a": {"b"},
+				"b": {"c"},
+				"d": {"a"},
+			},
+			wantPath:       []string{"a", "b", "c", "a"},
+			wantComponents: [][]string{{"a", "b", "c"}},
+			wantMsg:        "cycle detected: a -> b -> c -> a",
+		},
+		{
+			name: "self dependency",
+			tasks: map[string][]string{
+				"build": {"build"},
+			},
+			wantPath:       []string{"build", "build"},
+			wantComponents: [][]string{{"build"}},
+			wantMsg:        "cycle detected: build -> build",
+		},
+		{
+			name: "every cycle reported",
+			tasks: map[string][]string{
+				"a":      {"b"},
+				"b":      {"a"},
+				"x":      {"y"},
+				"y":      {"z"},
+				"z":      {"x", "y"},
+				"deploy": {"a", "x"},
+			},
+			wantPath:       []string{"a", "b", "a"},
+			wantComponents: [][]string{{"a", "b"}, {"x", "y", "z"}},
+			wantMsg:        "cycle detected: a -> b -> a; x -> y -> z -> x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ResolveOrder(tt.tasks)
+
+			var cycleErr *CycleError
+			if !errors.As(err, &cycleErr) {
+				t.Fatalf("ResolveOrder() error = %v, want *CycleError", err)
+			}
+			if !reflect.DeepEqual(cycleErr.Path, tt.wantPath) {
+				t.Errorf("CycleError.Path = %v, want %v", cycleErr.Path, tt.wantPath)
+			}
+			if !reflect.DeepEqual(cycleErr.Components, tt.wantComponents) {
+				t.Errorf("CycleError.Components = %v, want %v", cycleErr.Components, tt.wantComponents)
+			}
+			if err.Error() != tt.wantMsg {
+				t.Errorf("CycleError.Error() = %v, want %v", err.Error(), tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestResolveOrderMissingDependencyError(t *testing.T) {
+	tasks := map[string][]string{
+		"deploy":  {"compile", "package"},
+		"compile": {},
+		"test":    {"lint"},
+	}
+
+	_, err := ResolveOrder(tasks)
+
+	var missingErr *MissingDependencyError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("ResolveOrder() error = %v, want *MissingDependencyError", err)
+	}
+	if missingErr.Task != "deploy" || missingErr.Dependency != "package" {
+		t.Errorf("MissingDependencyError = {%v %v}, want {deploy package}", missingErr.Task, missingErr.Dependency)
+	}
+	if !contains(err.Error(), "could not find task package") {
+		t.Errorf("MissingDependencyError.Error() = %v, want it to name the missing task", err)
+	}
+}
+
+func TestFindCyclesAcyclic(t *testing.T) {
+	if err := FindCycles(buildTasks); err != nil {
+		t.Errorf("FindCycles() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+type TaskFunc func(ctx context.Context, name string) error
+
+type TaskStatus int
+
+const (
+	StatusPending TaskStatus = iota
+	StatusSucceeded
+	StatusFailed
+	StatusSkipped
+	StatusCancelled
+)
+
+func (s TaskStatus) String() string {
+	switch s {
+	case StatusPending:
+		return "pending"
+	case StatusSucceeded:
+		return "succeeded"
+	case StatusFailed:
+		return "failed"
+	case StatusSkipped:
+		return "skipped"
+	case StatusCancelled:
+		return "cancelled"
+	}
+	return fmt.Sprintf("TaskStatus(%d)", int(s))
+}
+
+type FailurePolicy int
+
+const (
+	FailFast FailurePolicy = iota
+	ContinueOnError
+)
+
+type ExecOptions struct {
+	Workers int
+	Policy  FailurePolicy
+}
+
+type TaskResult struct {
+	Name             string
+	Status           TaskStatus
+	Duration         time.Duration
+	Err              error
+	SkippedBecauseOf string
+}
+
+type ExecReport struct {
+	Results   map[string]*TaskResult
+	Completed []string
+}
+
+func Execute(ctx context.Context, tasks map[string][]string, run TaskFunc, opts ExecOptions) (*ExecReport, error) {
+	order, err := ResolveOrder(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	report := &ExecReport{Results: make(map[string]*TaskResult, len(tasks))}
+	remaining := make(map[string]int, len(tasks))
+	var ready []string
+	for _, name := range order {
+		report.Results[name] = &TaskResult{Name: name, Status: StatusPending}
+		remaining[name] = len(tasks[name])
+		if remaining[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	dependents := dependentsOf(tasks)
+
+	jobs := make(chan string)
+	done := make(chan TaskResult, len(tasks))
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				start := time.Now()
+				err := run(ctx, name)
+				done <- TaskResult{Name: name, Duration: time.Since(start), Err: err}
+			}
+		}()
+	}
+
+	running := 0
+	stopped := false
+	firstFailure := ""
+	for {
+		for !stopped && running < workers && len(ready) > 0 {
+			if ctx.Err() != nil {
+				stopped = true
+				break
+			}
+			jobs <- ready[0]
+			ready = ready[1:]
+			running++
+		}
+		if running == 0 {
+			break
+		}
+
+		res := <-done
+		running--
+		r := report.Results[res.Name]
+		r.Duration = res.Duration
+		report.Completed = append(report.Completed, res.Name)
+
+		switch {
+		case res.Err == nil:
+			r.Status = StatusSucceeded
+			for _, d := range dependents[res.Name] {
+				remaining[d]--
+				if remaining[d] == 0 && report.Results[d].Status == StatusPending {
+					ready = append(ready, d)
+				}
+			}
+		case ctx.Err() != nil && errors.Is(res.Err, ctx.Err()):
+			r.Status = StatusCancelled
+			r.Err = res.Err
+			r.SkippedBecauseOf = firstFailure
+		default:
+			r.Status = StatusFailed
+			r.Err = res.Err
+			if firstFailure == "" {
+				firstFailure = res.Name
+			}
+			if opts.Policy == FailFast {
+				stopped = true
+				cancel()
+			} else {
+				skipDependents(report, dependents, res.Name)
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, name := range order {
+		r := report.Results[name]
+		if r.Status != StatusPending {
+			continue
+		}
+		r.Status = StatusCancelled
+		r.SkippedBecauseOf = firstFailure
+		if firstFailure == "" {
+			r.Err = parent.Err()
+		}
+	}
+
+	var errs []error
+	for _, name := range report.Completed {
+		if r := report.Results[name]; r.Status == StatusFailed {
+			errs = append(errs, fmt.Errorf("task %v failed: %w", name, r.Err))
+		}
+	}
+	if len(errs) == 0 && parent.Err() != nil && len(report.Completed) < len(order) {
+		return report, parent.Err()
+	}
+	return report, errors.Join(errs...)
+}
+
+func skipDependents(report *ExecReport, dependents map[string][]string, failed string) {
+	queue := []string{failed}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, d := range dependents[name] {
+			r := report.Results[d]
+			if r.Status != StatusPending {
+				continue
+			}
+			r.Status = StatusSkipped
+			r.SkippedBecauseOf = failed
+			queue = append(queue, d)
+		}
+	}
+}
+
+func dependentsOf(tasks map[string][]string) map[string][]string {
+	dependents := make(map[string][]string, len(tasks))
+	for task, deps := range tasks {
+		for _, dep := range deps {
+			dependents[dep] = append(dependents[dep], task)
+		}
+	}
+	return dependents
+}
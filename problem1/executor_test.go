@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var buildTasks = map[string][]string{
+	"compile":       {"download_deps"},
+	"run_tests":     {"compile"},
+	"create_image":  {"compile"},
+	"deploy":        {"run_tests", "create_image"},
+	"download_deps": {},
+}
+
+func TestExecuteRespectsDependencies(t *testing.T) {
+	var mu sync.Mutex
+	finished := make(map[string]bool)
+	run := func(ctx context.Context, name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, dep := range buildTasks[name] {
+			if !finished[dep] {
+				t.Errorf("Execute() started %v before dependency %v finished", name, dep)
+			}
+		}
+		finished[name] = true
+		return nil
+	}
+
+	report, err := Execute(context.Background(), buildTasks, run, ExecOptions{Workers: 4})
+	if err != nil {
+		t.Fatalf("Execute() unexpected error = %v", err)
+	}
+	if len(report.Completed) != len(buildTasks) {
+		t.Errorf("Execute() completed %d tasks, want %d", len(report.Completed), len(buildTasks))
+	}
+	for name, r := range report.Results {
+		if r.Status != StatusSucceeded {
+			t.Errorf("Execute() status of %v = %v, want %v", name, r.Status, StatusSucceeded)
+		}
+	}
+}
+
+func TestExecuteBoundedWorkers(t *testing.T) {
+	tasks := map[string][]string{
+		"a": {}, "b": {}, "c": {}, "d": {}, "e": {}, "f": {},
+	}
+
+	var mu sync.Mutex
+	active, peak := 0, 0
+	run := func(ctx context.Context, name string) error {
+		mu.Lock()
+		active++
+		if active > peak {
+			peak = active
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return nil
+	}
+
+	if _, err := Execute(context.Background(), tasks, run, ExecOptions{Workers: 2}); err != nil {
+		t.Fatalf("Execute() unexpected error = %v", err)
+	}
+	if peak != 2 {
+		t.Errorf("Execute() peak concurrency = %d, want 2", peak)
+	}
+}
+
+func TestExecuteFailFast(t *testing.T) {
+	tasks := map[string][]string{
+		"fail":  {},
+		"slow":  {},
+		"after": {"slow"},
+	}
+	boom := errors.New("boom")
+	run := func(ctx context.Context, name string) error {
+		switch name {
+		case "fail":
+			return boom
+		case "slow":
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return nil
+			}
+		}
+		return nil
+	}
+
+	report, err := Execute(context.Background(), tasks, run, ExecOptions{Workers: 2, Policy: FailFast})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Execute() error = %v, want %v", err, boom)
+	}
+
+	want := map[string]TaskStatus{
+		"fail":  StatusFailed,
+		"slow":  StatusCancelled,
+		"after": StatusCancelled,
+	}
+	for name, status := range want {
+		r := report.Results[name]
+		if r.Status != status {
+			t.Errorf("Execute() status of %v = %v, want %v", name, r.Status, status)
+		}
+		if name != "fail" && r.SkippedBecauseOf != "fail" {
+			t.Errorf("Execute() %v skipped because of %q, want %q", name, r.SkippedBecauseOf, "fail")
+		}
+	}
+}
+
+func TestExecuteContinueOnError(t *testing.T) {
+	boom := errors.New("boom")
+	run := func(ctx context.Context, name string) error {
+		if name == "run_tests" {
+			return boom
+		}
+		return nil
+	}
+
+	report, err := Execute(context.Background(), buildTasks, run, ExecOptions{Workers: 2, Policy: ContinueOnError})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Execute() error = %v, want %v", err, boom)
+	}
+
+	want := map[string]TaskStatus{
+		"download_deps": StatusSucceeded,
+		"compile":       StatusSucceeded,
+		"create_image":  StatusSucceeded,
+		"run_tests":     StatusFailed,
+		"deploy":        StatusSkipped,
+	}
+	for name, status := range want {
+		if got := report.Results[name].Status; got != status {
+			t.Errorf("Execute() status of %v = %v, want %v", name, got, status)
+		}
+	}
+	if got := report.Results["deploy"].SkippedBecauseOf; got != "run_tests" {
+		t.Errorf("Execute() deploy skipped because of %q, want %q", got, "run_tests")
+	}
+	if !errors.Is(report.Results["run_tests"].Err, boom) {
+		t.Errorf("Execute() run_tests error = %v, want %v", report.Results["run_tests"].Err, boom)
+	}
+}
+
+func TestExecuteParentContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := func(ctx context.Context, name string) error {
+		if name == "download_deps" {
+			cancel()
+		}
+		return nil
+	}
+
+	report, err := Execute(ctx, buildTasks, run, ExecOptions{Workers: 1})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Execute() error = %v, want %v", err, context.Canceled)
+	}
+	if got := report.Results["download_deps"].Status; got != StatusSucceeded {
+		t.Errorf("Execute() status of download_deps = %v, want %v", got, StatusSucceeded)
+	}
+	if got := report.Results["deploy"].Status; got != StatusCancelled {
+		t.Errorf("Execute() status of deploy = %v, want %v", got, StatusCancelled)
+	}
+}
+
+func TestExecuteInvalidGraph(t *testing.T) {
+	tasks := map[string][]string{
+		"task1": {"task2"},
+		"task2": {"task1"},
+	}
+	called := false
+	run := func(ctx context.Context, name string) error {
+		called = true
+		return nil
+	}
+
+	if _, err := Execute(context.Background(), tasks, run, ExecOptions{}); err == nil {
+		t.Errorf("Execute() expected error but got none")
+	}
+	if called {
+		t.Errorf("Execute() ran tasks of an invalid graph")
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ExportOptions struct {
+	Cycle        []string
+	CriticalPath []string
+	Subgraph     map[string][]string
+}
+
+const (
+	cycleColor    = "#d62728"
+	criticalColor = "#ff7f0e"
+	dimmedColor   = "#bbbbbb"
+)
+
+type exportNode struct {
+	name    string
+	missing bool
+	style   string
+}
+
+type exportEdge struct {
+	from, to string
+	style    string
+}
+
+func ExportDOT(tasks map[string][]string, opts ExportOptions) string {
+	nodes, edges := exportGraph(tasks, opts)
+
+	var b strings.Builder
+	b.WriteString("digraph tasks {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, n := range nodes {
+		var attrs []string
+		if n.missing {
+			attrs = append(attrs, "style=dashed")
+		}
+		switch n.style {
+		case "cycle":
+			attrs = append(attrs, fmt.Sprintf(`color="%s"`, cycleColor), "penwidth=2")
+		case "critical":
+			attrs = append(attrs, fmt.Sprintf(`color="%s"`, criticalColor), "penwidth=2")
+		case "dimmed":
+			attrs = append(attrs, fmt.Sprintf(`color="%s"`, dimmedColor), fmt.Sprintf(`fontcolor="%s"`, dimmedColor))
+		}
+		fmt.Fprintf(&b, "  %s%s;\n", dotQuote(n.name), dotAttrs(attrs))
+	}
+	for _, e := range edges {
+		var attrs []string
+		switch e.style {
+		case "cycle":
+			attrs = append(attrs, fmt.Sprintf(`color="%s"`, cycleColor), "penwidth=2")
+		case "critical":
+			attrs = append(attrs, fmt.Sprintf(`color="%s"`, criticalColor), "penwidth=2")
+		case "dimmed":
+			attrs = append(attrs, fmt.Sprintf(`color="%s"`, dimmedColor))
+		}
+		fmt.Fprintf(&b, "  %s -> %s%s;\n", dotQuote(e.from), dotQuote(e.to), dotAttrs(attrs))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func ExportMermaid(tasks map[string][]string, opts ExportOptions) string {
+	nodes, edges := exportGraph(tasks, opts)
+
+	ids := make(map[string]string, len(nodes))
+	classes := make(map[string][]string)
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for i, n := range nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.name] = id
+		label := strings.ReplaceAll(n.name, `"`, "#quot;")
+		if n.missing {
+			fmt.Fprintf(&b, "    %s([\"%s\"])\n", id, label)
+		} else {
+			fmt.Fprintf(&b, "    %s[\"%s\"]\n", id, label)
+		}
+		if n.style != "" {
+			classes[n.style] = append(classes[n.style], id)
+		}
+	}
+
+	links := make(map[string][]string)
+	for i, e := range edges {
+		fmt.Fprintf(&b, "    %s --> %s\n", ids[e.from], ids[e.to])
+		if e.style != "" {
+			links[e.style] = append(links[e.style], fmt.Sprint(i))
+		}
+	}
+
+	styles := []struct{ name, color, width string }{
+		{"cycle", cycleColor, "2px"},
+		{"critical", criticalColor, "2px"},
+		{"dimmed", dimmedColor, "1px"},
+	}
+	for _, s := range styles {
+		if len(classes[s.name]) > 0 {
+			fmt.Fprintf(&b, "    classDef %s stroke:%s,stroke-width:%s\n", s.name, s.color, s.width)
+			fmt.Fprintf(&b, "    class %s %s\n", strings.Join(classes[s.name], ","), s.name)
+		}
+		if len(links[s.name]) > 0 {
+			fmt.Fprintf(&b, "    linkStyle %s stroke:%s,stroke-width:%s\n", strings.Join(links[s.name], ","), s.color, s.width)
+		}
+	}
+	return b.String()
+}
+
+func exportGraph(tasks map[string][]string, opts ExportOptions) ([]exportNode, []exportEdge) {
+	cycleNodes, cycleEdges := pathSets(opts.Cycle, true)
+	criticalNodes, criticalEdges := pathSets(opts.CriticalPath, false)
+
+	nodeStyle := func(name string) string {
+		switch {
+		case cycleNodes[name]:
+			return "cycle"
+		case criticalNodes[name]:
+			return "critical"
+		case opts.Subgraph != nil:
+			if _, ok := opts.Subgraph[name]; !ok {
+				return "dimmed"
+			}
+		}
+		return ""
+	}
+
+	var nodes []exportNode
+	var edges []exportEdge
+	seen := make(map[string]bool, len(tasks))
+	var missing []string
+	for _, task := range sortedTasks(tasks) {
+		seen[task] = true
+		nodes = append(nodes, exportNode{name: task, style: nodeStyle(task)})
+	}
+	for _, task := range sortedTasks(tasks) {
+		for _, dep := range tasks[task] {
+			if _, ok := tasks[dep]; !ok && !seen[dep] {
+				seen[dep] = true
+				missing = append(missing, dep)
+			}
+
+			key := dep + "\x00" + task
+			style := ""
+			switch {
+			case cycleEdges[key]:
+				style = "cycle"
+			case criticalEdges[key]:
+				style = "critical"
+			case opts.Subgraph != nil && !dependsOn(opts.Subgraph, task, dep):
+				style = "dimmed"
+			}
+			edges = append(edges, exportEdge{from: dep, to: task, style: style})
+		}
+	}
+	for _, dep := range missing {
+		nodes = append(nodes, exportNode{name: dep, missing: true, style: nodeStyle(dep)})
+	}
+	return nodes, edges
+}
+
+func pathSets(path []string, dependencyOrder bool) (map[string]bool, map[string]bool) {
+	nodes := make(map[string]bool, len(path))
+	edges := make(map[string]bool, len(path))
+	for i, task := range path {
+		nodes[task] = true
+		if i == 0 {
+			continue
+		}
+		from, to := path[i-1], task
+		if dependencyOrder {
+			from, to = to, from
+		}
+		edges[from+"\x00"+to] = true
+	}
+	return nodes, edges
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func dotAttrs(attrs []string) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(attrs, ", ") + "]"
+}
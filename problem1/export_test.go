@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExportDOT(t *testing.T) {
+	tasks := map[string][]string{
+		"compile":  {"download"},
+		"deploy":   {"compile"},
+		"download": {},
+	}
+
+	want := `digraph tasks {
+  rankdir=LR;
+  node [shape=box];
+  "compile";
+  "deploy";
+  "download";
+  "download" -> "compile";
+  "compile" -> "deploy";
+}
+`
+	if got := ExportDOT(tasks, ExportOptions{}); got != want {
+		t.Errorf("ExportDOT() = \n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestExportDOTHighlights(t *testing.T) {
+	analysis, err := AnalyzeSchedule(buildTasks, buildDurations)
+	if err != nil {
+		t.Fatalf("AnalyzeSchedule() unexpected error = %v", err)
+	}
+	got := ExportDOT(buildTasks, ExportOptions{CriticalPath: analysis.CriticalPath})
+	for _, line := range []string{
+		`"run_tests" [color="#ff7f0e", penwidth=2];`,
+		`"compile" -> "run_tests" [color="#ff7f0e", penwidth=2];`,
+		`"create_image";`,
+		`"compile" -> "create_image";`,
+	} {
+		if !contains(got, line) {
+			t.Errorf("ExportDOT() missing %q in\n%v", line, got)
+		}
+	}
+
+	sub, _ := Subgraph(buildTasks, []string{"run_tests"}, nil)
+	got = ExportDOT(buildTasks, ExportOptions{Subgraph: sub})
+	for _, line := range []string{
+		`"deploy" [color="#bbbbbb", fontcolor="#bbbbbb"];`,
+		`"run_tests" -> "deploy" [color="#bbbbbb"];`,
+		`"compile" -> "run_tests";`,
+	} {
+		if !contains(got, line) {
+			t.Errorf("ExportDOT() missing %q in\n%v", line, got)
+		}
+	}
+}
+
+func TestExportDOTCycleAndMissing(t *testing.T) {
+	tasks := map[string][]string{
+		"a":      {"b"},
+		"b":      {"a"},
+		"deploy": {"a", "ghost"},
+	}
+	_, err := ResolveOrder(map[string][]string{"a": {"b"}, "b": {"a"}})
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("ResolveOrder() error = %v, want *CycleError", err)
+	}
+
+	got := ExportDOT(tasks, ExportOptions{Cycle: cycleErr.Path})
+	for _, line := range []string{
+		`"a" [color="#d62728", penwidth=2];`,
+		`"b" -> "a" [color="#d62728", penwidth=2];`,
+		`"a" -> "b" [color="#d62728", penwidth=2];`,
+		`"a" -> "deploy";`,
+		`"ghost" [style=dashed];`,
+	} {
+		if !contains(got, line) {
+			t.Errorf("ExportDOT() missing %q in\n%v", line, got)
+		}
+	}
+}
+
+func TestExportMermaid(t *testing.T) {
+	tasks := map[string][]string{
+		"compile":  {"download"},
+		"deploy":   {"compile", "lint"},
+		"download": {},
+		"lint":     {},
+	}
+
+	want := `flowchart LR
+    n0["compile"]
+    n1["deploy"]
+    n2["download"]
+    n3["lint"]
+    n2 --> n0
+    n0 --> n1
+    n3 --> n1
+    classDef critical stroke:#ff7f0e,stroke-width:2px
+    class n0,n1,n2 critical
+    linkStyle 0,1 stroke:#ff7f0e,stroke-width:2px
+`
+	got := ExportMermaid(tasks, ExportOptions{CriticalPath: []string{"download", "compile", "deploy"}})
+	if got != want {
+		t.Errorf("ExportMermaid() = \n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestExportMermaidEscapesLabels(t *testing.T) {
+	got := ExportMermaid(map[string][]string{`say "hi"`: {"missing"}}, ExportOptions{})
+	if !contains(got, `n0["say #quot;hi#quot;"]`) {
+		t.Errorf("ExportMermaid() did not escape quotes:\n%v", got)
+	}
+	if !contains(got, `n1(["missing"])`) {
+		t.Errorf("ExportMermaid() did not mark missing dependency:\n%v", got)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import "sort"
+
+type Graph struct {
+	names []string
+	ids   map[string]int32
+	deps  [][]int32
+}
+
+func NewGraph(tasks map[string][]string) (*Graph, error) {
+	g, missing := internGraph(tasks)
+	if missing != nil {
+		return nil, missing
+	}
+	return g, nil
+}
+
+func internGraph(tasks map[string][]string) (*Graph, *MissingDependencyError) {
+	names := sortedTasks(tasks)
+	g := &Graph{
+		names: names,
+		ids:   make(map[string]int32, len(names)),
+		deps:  make([][]int32, len(names)),
+	}
+	for i, name := range names {
+		g.ids[name] = int32(i)
+	}
+
+	edges := 0
+	for _, deps := range tasks {
+		edges += len(deps)
+	}
+	flat := make([]int32, 0, edges)
+
+	var missing *MissingDependencyError
+	for i, name := range names {
+		start := len(flat)
+		for _, dep := range tasks[name] {
+			id, ok := g.ids[dep]
+			if !ok {
+				if missing == nil {
+					missing = &MissingDependencyError{Task: name, Dependency: dep}
+				}
+				continue
+			}
+			flat = append(flat, id)
+		}
+		g.deps[i] = flat[start:len(flat):len(flat)]
+	}
+	return g, missing
+}
+
+// Order walks the graph depth first, visiting tasks by name and dependencies
+// in the order they are listed, and emits each task after its dependencies.
+// The walk keeps its own stack, so deep chains cannot overflow the goroutine
+// stack.
+func (g *Graph) Order() ([]string, error) {
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	type frame struct {
+		node int32
+		edge int
+	}
+
+	n := len(g.names)
+	state := make([]uint8, n)
+	var order []string
+	var call []frame
+	for s := 0; s < n; s++ {
+		if state[s] != unvisited {
+			continue
+		}
+		state[s] = onPath
+		call = append(call, frame{node: int32(s)})
+		for len(call) > 0 {
+			f := &call[len(call)-1]
+			v := f.node
+			if f.edge < len(g.deps[v]) {
+				w := g.deps[v][f.edge]
+				f.edge++
+				switch state[w] {
+				case onPath:
+					return nil, g.cycles()
+				case unvisited:
+					state[w] = onPath
+					call = append(call, frame{node: w})
+				}
+				continue
+			}
+			state[v] = done
+			order = append(order, g.names[v])
+			call = call[:len(call)-1]
+		}
+	}
+	return order, nil
+}
+
+func (g *Graph) cycles() *CycleError {
+	var components [][]int32
+	for _, scc := range g.stronglyConnected() {
+		if len(scc) == 1 && !g.hasEdge(scc[0], scc[0]) {
+			continue
+		}
+		sort.Slice(scc, func(i, j int) bool { return scc[i] < scc[j] })
+		components = append(components, scc)
+	}
+	if len(components) == 0 {
+		return nil
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i][0] < components[j][0]
+	})
+
+	err := &CycleError{}
+	for _, scc := range components {
+		names := make([]string, len(scc))
+		for i, v := range scc {
+			names[i] = g.names[v]
+		}
+		err.Components = append(err.Components, names)
+		err.Cycles = append(err.Cycles, g.cycleThrough(scc))
+	}
+	err.Path = err.Cycles[0]
+	return err
+}
+
+func (g *Graph) stronglyConnected() [][]int32 {
+	type frame struct {
+		node int32
+		edge int
+	}
+
+	n := len(g.names)
+	index := make([]int32, n)
+	low := make([]int32, n)
+	onStack := make([]bool, n)
+	for i := range index {
+		index[i] = -1
+	}
+	var stack []int32
+	var call []frame
+	var components [][]int32
+	next := int32(0)
+
+	visit := func(v int32) {
+		index[v] = next
+		low[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+		call = append(call, frame{node: v})
+	}
+
+	for s := 0; s < n; s++ {
+		if index[s] != -1 {
+			continue
+		}
+		visit(int32(s))
+		for len(call) > 0 {
+			f := &call[len(call)-1]
+			v := f.node
+			if f.edge < len(g.deps[v]) {
+				w := g.deps[v][f.edge]
+				f.edge++
+				if index[w] == -1 {
+					visit(w)
+				} else if onStack[w] {
+					low[v] = min(low[v], index[w])
+				}
+				continue
+			}
+
+			if low[v] == index[v] {
+				var scc []int32
+				for {
+					top := stack[len(stack)-1]
+					stack = stack[:len(stack)-1]
+					onStack[top] = false
+					scc = append(scc, top)
+					if top == v {
+						break
+					}
+				}
+				components = append(components, scc)
+			}
+			call = call[:len(call)-1]
+			if len(call) > 0 {
+				u := call[len(call)-1].node
+				low[u] = min(low[u], low[v])
+			}
+		}
+	}
+	return components
+}
+
+func (g *Graph) cycleThrough(scc []int32) []string {
+	start := scc[0]
+	inSCC := make(map[int32]bool, len(scc))
+	for _, v := range scc {
+		inSCC[v] = true
+	}
+
+	parent := map[int32]int32{}
+	queue := []int32{start}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, w := range g.deps[v] {
+			if w == start {
+				path := []string{g.names[start]}
+				for u := v; u != start; u = parent[u] {
+					path = append(path, g.names[u])
+				}
+				path = append(path, g.names[start])
+				for i, j := 1, len(path)-2; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				return path
+			}
+			if _, seen := parent[w]; seen || !inSCC[w] {
+				continue
+			}
+			parent[w] = v
+			queue = append(queue, w)
+		}
+	}
+	return nil
+}
+
+func (g *Graph) hasEdge(from, to int32) bool {
+	for _, d := range g.deps[from] {
+		if d == to {
+			return true
+		}
+	}
+	return false
+}
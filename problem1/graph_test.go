@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestGraphOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		tasks   map[string][]string
+		want    []string
+		wantErr error
+	}{
+		{
+			name:  "basic",
+			tasks: buildTasks,
+			want:  []string{"download_deps", "compile", "create_image", "run_tests", "deploy"},
+		},
+		{"empty", map[string][]string{}, nil, nil},
+		{
+			name:  "duplicate dependencies",
+			tasks: map[string][]string{"a": {"b", "b"}, "b": {}},
+			want:  []string{"b", "a"},
+		},
+		{
+			name:  "direct cycle",
+			tasks: map[string][]string{"task1": {"task2"}, "task2": {"task1"}},
+			wantErr: &CycleError{
+				Path:       []string{"task1", "task2", "task1"},
+				Cycles:     [][]string{{"task1", "task2", "task1"}},
+				Components: [][]string{{"task1", "task2"}},
+			},
+		},
+		{
+			name:  "self dependency",
+			tasks: map[string][]string{"build": {"build"}},
+			wantErr: &CycleError{
+				Path:       []string{"build", "build"},
+				Cycles:     [][]string{{"build", "build"}},
+				Components: [][]string{{"build"}},
+			},
+		},
+		{
+			name: "several cycles",
+			tasks: map[string][]string{
+				"a": {"b"}, "b": {"a"},
+				"x": {"y"}, "y": {"z"}, "z": {"x", "y"},
+				"deploy": {"a", "x"},
+			},
+			wantErr: &CycleError{
+				Path:       []string{"a", "b", "a"},
+				Cycles:     [][]string{{"a", "b", "a"}, {"x", "y", "z", "x"}},
+				Components: [][]string{{"a", "b"}, {"x", "y", "z"}},
+			},
+		},
+		{
+			name: "missing dependency",
+			tasks: map[string][]string{
+				"deploy": {"compile", "package"}, "compile": {}, "test": {"lint"},
+			},
+			wantErr: &MissingDependencyError{Task: "deploy", Dependency: "package"},
+		},
+		{
+			name: "missing dependency and cycle",
+			tasks: map[string][]string{
+				"a": {"b"}, "b": {"a"}, "c": {"ghost"},
+			},
+			wantErr: &MissingDependencyError{Task: "c", Dependency: "ghost"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveOrder(tt.tasks)
+			if !reflect.DeepEqual(err, tt.wantErr) {
+				t.Fatalf("ResolveOrder() error = %#v, want %#v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveOrder() = %v, want %v", got, tt.want)
+			}
+
+			g, err := NewGraph(tt.tasks)
+			if missing, ok := tt.wantErr.(*MissingDependencyError); ok {
+				if !reflect.DeepEqual(err, missing) {
+					t.Errorf("NewGraph() error = %#v, want %#v", err, missing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewGraph() unexpected error = %v", err)
+			}
+			if order, _ := g.Order(); !reflect.DeepEqual(order, tt.want) {
+				t.Errorf("Graph.Order() = %v, want %v", order, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveOrderDeepChain(t *testing.T) {
+	const n = 200000
+	tasks := chainGraph(n)
+	tasks[taskName(0)] = []string{taskName(n - 1)}
+
+	_, err := ResolveOrder(tasks)
+	cycleErr, ok := err.(*CycleError)
+	if !ok {
+		t.Fatalf("ResolveOrder() error = %v, want *CycleError", err)
+	}
+	if len(cycleErr.Path) != n+1 {
+		t.Errorf("ResolveOrder() cycle length = %d, want %d", len(cycleErr.Path), n+1)
+	}
+
+	tasks[taskName(0)] = nil
+	order, err := ResolveOrder(tasks)
+	if err != nil {
+		t.Fatalf("ResolveOrder() unexpected error = %v", err)
+	}
+	for i, task := range order {
+		if task != taskName(i) {
+			t.Fatalf("ResolveOrder()[%d] = %v, want %v", i, task, taskName(i))
+		}
+	}
+}
+
+func taskName(i int) string {
+	return fmt.Sprintf("task%07d", i)
+}
+
+func chainGraph(n int) map[string][]string {
+	tasks := make(map[string][]string, n)
+	tasks[taskName(0)] = nil
+	for i := 1; i < n; i++ {
+		tasks[taskName(i)] = []string{taskName(i - 1)}
+	}
+	return tasks
+}
+
+func layeredGraph(n int) map[string][]string {
+	const width = 100
+	tasks := make(map[string][]string, n)
+	for i := 0; i < n; i++ {
+		var deps []string
+		if i >= width {
+			layer := i/width*width - width
+			deps = []string{taskName(layer + i%width), taskName(layer + (i+1)%width), taskName(layer + (i+7)%width)}
+		}
+		tasks[taskName(i)] = deps
+	}
+	return tasks
+}
+
+// BenchmarkResolveOrder reports ns/task, which stays flat as the graphs grow
+// when resolving scales linearly.
+func BenchmarkResolveOrder(b *testing.B) {
+	for _, shape := range []struct {
+		name  string
+		build func(int) map[string][]string
+	}{
+		{"chain", chainGraph},
+		{"layered", layeredGraph},
+	} {
+		for _, n := range []int{1000, 10000, 100000} {
+			tasks := shape.build(n)
+			b.Run(fmt.Sprintf("%s-%d", shape.name, n), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := ResolveOrder(tasks); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(n), "ns/task")
+			})
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import "sort"
+
+type ImpactedTask struct {
+	Name     string
+	Distance int
+}
+
+func ReverseGraph(tasks map[string][]string) map[string][]string {
+	reverse := make(map[string][]string, len(tasks))
+	for _, task := range sortedTasks(tasks) {
+		if _, ok := reverse[task]; !ok {
+			reverse[task] = []string{}
+		}
+		seen := make(map[string]bool, len(tasks[task]))
+		for _, dep := range tasks[task] {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			reverse[dep] = append(reverse[dep], task)
+		}
+	}
+	return reverse
+}
+
+func Impact(tasks map[string][]string, changed []string) ([]ImpactedTask, error) {
+	order, err := ResolveOrder(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	distance := make(map[string]int)
+	var queue []string
+	for _, task := range changed {
+		if _, ok := tasks[task]; !ok {
+			return nil, &UnknownTaskError{Task: task}
+		}
+		if _, seen := distance[task]; !seen {
+			distance[task] = 0
+			queue = append(queue, task)
+		}
+	}
+
+	reverse := ReverseGraph(tasks)
+	for len(queue) > 0 {
+		task := queue[0]
+		queue = queue[1:]
+		for _, d := range reverse[task] {
+			if _, seen := distance[d]; !seen {
+				distance[d] = distance[task] + 1
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	position := make(map[string]int, len(order))
+	for i, task := range order {
+		position[task] = i
+	}
+	impacted := make([]ImpactedTask, 0, len(distance))
+	for task, d := range distance {
+		impacted = append(impacted, ImpactedTask{Name: task, Distance: d})
+	}
+	sort.Slice(impacted, func(i, j int) bool {
+		return position[impacted[i].Name] < position[impacted[j].Name]
+	})
+	return impacted, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReverseGraph(t *testing.T) {
+	tasks := map[string][]string{
+		"compile":  {"download", "download"},
+		"test":     {"compile"},
+		"build":    {"compile"},
+		"download": {},
+	}
+
+	want := map[string][]string{
+		"build":    {},
+		"compile":  {"build", "test"},
+		"download": {"compile"},
+		"test":     {},
+	}
+	if got := ReverseGraph(tasks); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReverseGraph() = %v, want %v", got, want)
+	}
+}
+
+func TestImpact(t *testing.T) {
+	tasks := map[string][]string{
+		"download":   {},
+		"compile":    {"download"},
+		"lint":       {},
+		"test":       {"compile"},
+		"build":      {"compile"},
+		"package":    {"build"},
+		"deploy":     {"test", "package"},
+		"docs":       {"lint"},
+		"distribute": {"package", "docs"},
+	}
+
+	tests := []struct {
+		name    string
+		changed []string
+		want    []ImpactedTask
+	}{
+		{
+			name:    "single change",
+			changed: []string{"compile"},
+			want: []ImpactedTask{
+				{"compile", 0},
+				{"build", 1},
+				{"test", 1},
+				{"package", 2},
+				{"deploy", 2},
+				{"distribute", 3},
+			},
+		},
+		{
+			name:    "leaf change",
+			changed: []string{"deploy"},
+			want:    []ImpactedTask{{"deploy", 0}},
+		},
+		{
+			name:    "multiple changes use the nearest distance",
+			changed: []string{"lint", "package"},
+			want: []ImpactedTask{
+				{"package", 0},
+				{"deploy", 1},
+				{"lint", 0},
+				{"docs", 1},
+				{"distribute", 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Impact(tasks, tt.changed)
+			if err != nil {
+				t.Fatalf("Impact() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Impact() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImpactErrors(t *testing.T) {
+	var unknownErr *UnknownTaskError
+	if _, err := Impact(buildTasks, []string{"lint"}); !errors.As(err, &unknownErr) {
+		t.Errorf("Impact() error = %v, want *UnknownTaskError", err)
+	}
+
+	var cycleErr *CycleError
+	if _, err := Impact(map[string][]string{"a": {"b"}, "b": {"a"}}, []string{"a"}); !errors.As(err, &cycleErr) {
+		t.Errorf("Impact() error = %v, want *CycleError", err)
+	}
+}
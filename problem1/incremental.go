@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const buildStateVersion = 1
+
+type Input struct {
+	Path string
+	Hash string
+}
+
+func (in Input) Fingerprint() (string, error) {
+	if in.Hash != "" {
+		return in.Hash, nil
+	}
+	data, err := os.ReadFile(in.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint %v: %w", in.Path, err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func FingerprintInputs(inputs []Input) (string, error) {
+	entries := make([]string, 0, len(inputs))
+	for _, in := range inputs {
+		fp, err := in.Fingerprint()
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, in.Path+"\x00"+fp)
+	}
+	sort.Strings(entries)
+
+	h := sha256.New()
+	for _, e := range entries {
+		h.Write([]byte(e))
+		h.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type BuildState struct {
+	Version      int               `json:"version"`
+	Fingerprints map[string]string `json:"fingerprints"`
+}
+
+func NewBuildState() *BuildState {
+	return &BuildState{
+		Version:      buildStateVersion,
+		Fingerprints: make(map[string]string),
+	}
+}
+
+func LoadBuildState(path string) (*BuildState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewBuildState(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build state: %w", err)
+	}
+
+	state := NewBuildState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse build state: %w", err)
+	}
+	if state.Version > buildStateVersion {
+		return nil, fmt.Errorf("unsupported build state version %d", state.Version)
+	}
+	if state.Fingerprints == nil {
+		state.Fingerprints = make(map[string]string)
+	}
+	return state, nil
+}
+
+func (s *BuildState) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed marshalling the build state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create build state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the build state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the build state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the build state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace the build state: %w", err)
+	}
+	return nil
+}
+
+type IncrementalPlan struct {
+	Order        []string
+	Changed      []string
+	Fingerprints map[string]string
+}
+
+func PlanIncremental(tasks map[string][]string, inputs map[string][]Input, state *BuildState) (*IncrementalPlan, error) {
+	order, err := ResolveOrder(tasks)
+	if err != nil {
+		return nil, err
+	}
+	for task := range inputs {
+		if _, ok := tasks[task]; !ok {
+			return nil, &UnknownTaskError{Task: task}
+		}
+	}
+
+	plan := &IncrementalPlan{Fingerprints: make(map[string]string, len(tasks))}
+	stale := make(map[string]bool)
+	var queue []string
+	for _, task := range order {
+		fp, err := FingerprintInputs(inputs[task])
+		if err != nil {
+			return nil, fmt.Errorf("task %v: %w", task, err)
+		}
+		plan.Fingerprints[task] = fp
+		if prev, ok := state.Fingerprints[task]; !ok || prev != fp {
+			plan.Changed = append(plan.Changed, task)
+			stale[task] = true
+			queue = append(queue, task)
+		}
+	}
+	sort.Strings(plan.Changed)
+
+	dependents := dependentsOf(tasks)
+	for len(queue) > 0 {
+		task := queue[0]
+		queue = queue[1:]
+		for _, d := range dependents[task] {
+			if !stale[d] {
+				stale[d] = true
+				queue = append(queue, d)
+			}
+		}
+	}
+
+	for _, task := range order {
+		if stale[task] {
+			plan.Order = append(plan.Order, task)
+		}
+	}
+	return plan, nil
+}
+
+func (p *IncrementalPlan) Graph(tasks map[string][]string) map[string][]string {
+	planned := make(map[string]bool, len(p.Order))
+	for _, task := range p.Order {
+		planned[task] = true
+	}
+
+	graph := make(map[string][]string, len(p.Order))
+	for _, task := range p.Order {
+		deps := []string{}
+		for _, dep := range tasks[task] {
+			if planned[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		graph[task] = deps
+	}
+	return graph
+}
+
+func (p *IncrementalPlan) Record(state *BuildState, report *ExecReport) {
+	for _, task := range p.Order {
+		if r, ok := report.Results[task]; ok && r.Status == StatusSucceeded {
+			state.Fingerprints[task] = p.Fingerprints[task]
+		} else {
+			delete(state.Fingerprints, task)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFingerprintInputs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(path, []byte("package main"), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	first, err := FingerprintInputs([]Input{{Path: path}, {Path: "go.sum", Hash: "abc"}})
+	if err != nil {
+		t.Fatalf("FingerprintInputs() unexpected error = %v", err)
+	}
+	reordered, _ := FingerprintInputs([]Input{{Path: "go.sum", Hash: "abc"}, {Path: path}})
+	if first != reordered {
+		t.Errorf("FingerprintInputs() depends on input order: %v != %v", first, reordered)
+	}
+
+	if err := os.WriteFile(path, []byte("package main // changed"), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+	changed, _ := FingerprintInputs([]Input{{Path: path}, {Path: "go.sum", Hash: "abc"}})
+	if changed == first {
+		t.Errorf("FingerprintInputs() did not change after file content changed")
+	}
+
+	if _, err := FingerprintInputs([]Input{{Path: filepath.Join(dir, "missing")}}); err == nil {
+		t.Errorf("FingerprintInputs() expected error for missing file but got none")
+	}
+}
+
+func TestPlanIncremental(t *testing.T) {
+	inputs := map[string][]Input{
+		"download_deps": {{Path: "go.sum", Hash: "v1"}},
+		"compile":       {{Path: "main.go", Hash: "v1"}},
+		"run_tests":     {{Path: "main_test.go", Hash: "v1"}},
+		"create_image":  {{Path: "Dockerfile", Hash: "v1"}},
+	}
+
+	state := NewBuildState()
+	plan, err := PlanIncremental(buildTasks, inputs, state)
+	if err != nil {
+		t.Fatalf("PlanIncremental() unexpected error = %v", err)
+	}
+	if len(plan.Order) != len(buildTasks) {
+		t.Errorf("PlanIncremental() first build = %v, want every task", plan.Order)
+	}
+	for task, fp := range plan.Fingerprints {
+		state.Fingerprints[task] = fp
+	}
+
+	plan, _ = PlanIncremental(buildTasks, inputs, state)
+	if len(plan.Order) != 0 {
+		t.Errorf("PlanIncremental() up-to-date build = %v, want nothing", plan.Order)
+	}
+
+	inputs["run_tests"] = []Input{{Path: "main_test.go", Hash: "v2"}}
+	plan, _ = PlanIncremental(buildTasks, inputs, state)
+	if want := []string{"run_tests", "deploy"}; !reflect.DeepEqual(plan.Order, want) {
+		t.Errorf("PlanIncremental() order = %v, want %v", plan.Order, want)
+	}
+	if want := []string{"run_tests"}; !reflect.DeepEqual(plan.Changed, want) {
+		t.Errorf("PlanIncremental() changed = %v, want %v", plan.Changed, want)
+	}
+
+	inputs["compile"] = []Input{{Path: "main.go", Hash: "v2"}}
+	plan, _ = PlanIncremental(buildTasks, inputs, state)
+	if want := []string{"compile", "create_image", "run_tests", "deploy"}; !reflect.DeepEqual(plan.Order, want) {
+		t.Errorf("PlanIncremental() order = %v, want %v", plan.Order, want)
+	}
+
+	_, err = PlanIncremental(buildTasks, map[string][]Input{"lint": nil}, state)
+	var unknownErr *UnknownTaskError
+	if !errors.As(err, &unknownErr) {
+		t.Errorf("PlanIncremental() error = %v, want *UnknownTaskError", err)
+	}
+}
+
+func TestIncrementalBuildRoundTrip(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	inputs := map[string][]Input{
+		"compile":   {{Path: "main.go", Hash: "v1"}},
+		"run_tests": {{Path: "main_test.go", Hash: "v1"}},
+	}
+	boom := errors.New("boom")
+	failing := ""
+	var ran []string
+	run := func(ctx context.Context, name string) error {
+		ran = append(ran, name)
+		if name == failing {
+			return boom
+		}
+		return nil
+	}
+
+	build := func() {
+		t.Helper()
+		ran = nil
+		state, err := LoadBuildState(statePath)
+		if err != nil {
+			t.Fatalf("LoadBuildState() unexpected error = %v", err)
+		}
+		plan, err := PlanIncremental(buildTasks, inputs, state)
+		if err != nil {
+			t.Fatalf("PlanIncremental() unexpected error = %v", err)
+		}
+		report, _ := Execute(context.Background(), plan.Graph(buildTasks), run, ExecOptions{Workers: 1, Policy: ContinueOnError})
+		plan.Record(state, report)
+		if err := state.Save(statePath); err != nil {
+			t.Fatalf("BuildState.Save() unexpected error = %v", err)
+		}
+	}
+
+	build()
+	if len(ran) != len(buildTasks) {
+		t.Errorf("first build ran %v, want every task", ran)
+	}
+
+	build()
+	if len(ran) != 0 {
+		t.Errorf("second build ran %v, want nothing", ran)
+	}
+
+	inputs["compile"] = []Input{{Path: "main.go", Hash: "v2"}}
+	failing = "deploy"
+	build()
+	if len(ran) != 4 {
+		t.Errorf("third build ran %v, want compile and everything downstream", ran)
+	}
+
+	failing = ""
+	build()
+	if !reflect.DeepEqual(ran, []string{"deploy"}) {
+		t.Errorf("fourth build ran %v, want only the previously failed deploy", ran)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(statePath))
+	if len(entries) != 1 {
+		t.Errorf("BuildState.Save() left %d files behind, want 1", len(entries))
+	}
+}
+
+func TestLoadBuildStateRejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte(`{"version": 99, "fingerprints": {}}`), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+	if _, err := LoadBuildState(path); err == nil {
+		t.Errorf("LoadBuildState() expected error for newer version but got none")
+	}
+}
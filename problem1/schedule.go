@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+type TaskTiming struct {
+	Duration       time.Duration
+	EarliestStart  time.Duration
+	EarliestFinish time.Duration
+	LatestStart    time.Duration
+	LatestFinish   time.Duration
+	Slack          time.Duration
+}
+
+type ScheduleAnalysis struct {
+	Tasks        map[string]TaskTiming
+	CriticalPath []string
+	Makespan     time.Duration
+}
+
+func AnalyzeSchedule(tasks map[string][]string, durations map[string]time.Duration) (*ScheduleAnalysis, error) {
+	order, err := ResolveOrder(tasks)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDurations(tasks, durations); err != nil {
+		return nil, err
+	}
+
+	analysis := &ScheduleAnalysis{Tasks: make(map[string]TaskTiming, len(tasks))}
+	for _, task := range order {
+		var start time.Duration
+		for _, dep := range tasks[task] {
+			start = max(start, analysis.Tasks[dep].EarliestFinish)
+		}
+		finish := start + durations[task]
+		analysis.Tasks[task] = TaskTiming{
+			Duration:       durations[task],
+			EarliestStart:  start,
+			EarliestFinish: finish,
+		}
+		analysis.Makespan = max(analysis.Makespan, finish)
+	}
+
+	dependents := dependentsOf(tasks)
+	for i := len(order) - 1; i >= 0; i-- {
+		task := order[i]
+		timing := analysis.Tasks[task]
+		timing.LatestFinish = analysis.Makespan
+		for _, d := range dependents[task] {
+			timing.LatestFinish = min(timing.LatestFinish, analysis.Tasks[d].LatestStart)
+		}
+		timing.LatestStart = timing.LatestFinish - timing.Duration
+		timing.Slack = timing.LatestStart - timing.EarliestStart
+		analysis.Tasks[task] = timing
+	}
+
+	analysis.CriticalPath = criticalPath(tasks, analysis)
+	return analysis, nil
+}
+
+func criticalPath(tasks map[string][]string, analysis *ScheduleAnalysis) []string {
+	current := ""
+	for _, task := range sortedTasks(tasks) {
+		timing := analysis.Tasks[task]
+		if timing.Slack == 0 && timing.EarliestFinish == analysis.Makespan {
+			current = task
+			break
+		}
+	}
+	if current == "" {
+		return nil
+	}
+
+	path := []string{current}
+	for {
+		start := analysis.Tasks[current].EarliestStart
+		next := ""
+		for _, dep := range tasks[current] {
+			timing := analysis.Tasks[dep]
+			if timing.Slack == 0 && timing.EarliestFinish == start && (next == "" || dep < next) {
+				next = dep
+			}
+		}
+		if next == "" {
+			break
+		}
+		path = append(path, next)
+		current = next
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+type ScheduledTask struct {
+	Name   string
+	Worker int
+	Start  time.Duration
+	Finish time.Duration
+}
+
+type Simulation struct {
+	Workers  int
+	Tasks    []ScheduledTask
+	Makespan time.Duration
+}
+
+func SimulateSchedule(tasks map[string][]string, durations map[string]time.Duration, workers int) (*Simulation, error) {
+	if workers <= 0 {
+		return nil, fmt.Errorf("workers must be positive, got %d", workers)
+	}
+	analysis, err := AnalyzeSchedule(tasks, durations)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[string]int, len(tasks))
+	var ready []string
+	for task, deps := range tasks {
+		remaining[task] = len(deps)
+		if len(deps) == 0 {
+			ready = append(ready, task)
+		}
+	}
+	dependents := dependentsOf(tasks)
+
+	priority := func(task string) time.Duration {
+		return analysis.Makespan - analysis.Tasks[task].LatestStart
+	}
+
+	sim := &Simulation{Workers: workers}
+	running := make([]*ScheduledTask, workers)
+	now := time.Duration(0)
+	for len(sim.Tasks) < len(tasks) {
+		sort.Slice(ready, func(i, j int) bool {
+			pi, pj := priority(ready[i]), priority(ready[j])
+			if pi != pj {
+				return pi > pj
+			}
+			return ready[i] < ready[j]
+		})
+		for w := 0; w < workers && len(ready) > 0; w++ {
+			if running[w] != nil {
+				continue
+			}
+			task := ready[0]
+			ready = ready[1:]
+			running[w] = &ScheduledTask{Name: task, Worker: w, Start: now, Finish: now + durations[task]}
+		}
+
+		next := time.Duration(-1)
+		for _, st := range running {
+			if st != nil && (next < 0 || st.Finish < next) {
+				next = st.Finish
+			}
+		}
+		now = next
+
+		for w, st := range running {
+			if st == nil || st.Finish != now {
+				continue
+			}
+			sim.Tasks = append(sim.Tasks, *st)
+			running[w] = nil
+			for _, d := range dependents[st.Name] {
+				remaining[d]--
+				if remaining[d] == 0 {
+					ready = append(ready, d)
+				}
+			}
+		}
+		sim.Makespan = max(sim.Makespan, now)
+	}
+
+	sort.SliceStable(sim.Tasks, func(i, j int) bool {
+		if sim.Tasks[i].Start != sim.Tasks[j].Start {
+			return sim.Tasks[i].Start < sim.Tasks[j].Start
+		}
+		return sim.Tasks[i].Worker < sim.Tasks[j].Worker
+	})
+	return sim, nil
+}
+
+func checkDurations(tasks map[string][]string, durations map[string]time.Duration) error {
+	for task, d := range durations {
+		if _, ok := tasks[task]; !ok {
+			return &UnknownTaskError{Task: task}
+		}
+		if d < 0 {
+			return fmt.Errorf("task %v has negative duration %v", task, d)
+		}
+	}
+	return nil
+}
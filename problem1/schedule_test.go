@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var buildDurations = map[string]time.Duration{
+	"download_deps": 2 * time.Minute,
+	"compile":       5 * time.Minute,
+	"run_tests":     8 * time.Minute,
+	"create_image":  3 * time.Minute,
+	"deploy":        1 * time.Minute,
+}
+
+func TestAnalyzeSchedule(t *testing.T) {
+	analysis, err := AnalyzeSchedule(buildTasks, buildDurations)
+	if err != nil {
+		t.Fatalf("AnalyzeSchedule() unexpected error = %v", err)
+	}
+
+	if analysis.Makespan != 16*time.Minute {
+		t.Errorf("AnalyzeSchedule() makespan = %v, want %v", analysis.Makespan, 16*time.Minute)
+	}
+	wantPath := []string{"download_deps", "compile", "run_tests", "deploy"}
+	if !reflect.DeepEqual(analysis.CriticalPath, wantPath) {
+		t.Errorf("AnalyzeSchedule() critical path = %v, want %v", analysis.CriticalPath, wantPath)
+	}
+
+	want := map[string]TaskTiming{
+		"download_deps": {Duration: 2 * time.Minute, EarliestStart: 0, EarliestFinish: 2 * time.Minute, LatestStart: 0, LatestFinish: 2 * time.Minute},
+		"compile":       {Duration: 5 * time.Minute, EarliestStart: 2 * time.Minute, EarliestFinish: 7 * time.Minute, LatestStart: 2 * time.Minute, LatestFinish: 7 * time.Minute},
+		"run_tests":     {Duration: 8 * time.Minute, EarliestStart: 7 * time.Minute, EarliestFinish: 15 * time.Minute, LatestStart: 7 * time.Minute, LatestFinish: 15 * time.Minute},
+		"create_image":  {Duration: 3 * time.Minute, EarliestStart: 7 * time.Minute, EarliestFinish: 10 * time.Minute, LatestStart: 12 * time.Minute, LatestFinish: 15 * time.Minute, Slack: 5 * time.Minute},
+		"deploy":        {Duration: 1 * time.Minute, EarliestStart: 15 * time.Minute, EarliestFinish: 16 * time.Minute, LatestStart: 15 * time.Minute, LatestFinish: 16 * time.Minute},
+	}
+	for task, timing := range want {
+		if got := analysis.Tasks[task]; got != timing {
+			t.Errorf("AnalyzeSchedule() %v = %+v, want %+v", task, got, timing)
+		}
+	}
+}
+
+func TestAnalyzeScheduleErrors(t *testing.T) {
+	_, err := AnalyzeSchedule(buildTasks, map[string]time.Duration{"lint": time.Second})
+	var unknownErr *UnknownTaskError
+	if !errors.As(err, &unknownErr) {
+		t.Errorf("AnalyzeSchedule() error = %v, want *UnknownTaskError", err)
+	}
+
+	if _, err := AnalyzeSchedule(buildTasks, map[string]time.Duration{"compile": -time.Second}); err == nil {
+		t.Errorf("AnalyzeSchedule() expected error for negative duration but got none")
+	}
+
+	cyclic := map[string][]string{"a": {"b"}, "b": {"a"}}
+	var cycleErr *CycleError
+	if _, err := AnalyzeSchedule(cyclic, nil); !errors.As(err, &cycleErr) {
+		t.Errorf("AnalyzeSchedule() error = %v, want *CycleError", err)
+	}
+}
+
+func TestSimulateSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		workers      int
+		wantMakespan time.Duration
+	}{
+		{"single worker runs everything serially", 1, 19 * time.Minute},
+		{"two workers reach the critical path", 2, 16 * time.Minute},
+		{"extra workers do not help", 8, 16 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, err := SimulateSchedule(buildTasks, buildDurations, tt.workers)
+			if err != nil {
+				t.Fatalf("SimulateSchedule() unexpected error = %v", err)
+			}
+			if sim.Makespan != tt.wantMakespan {
+				t.Errorf("SimulateSchedule() makespan = %v, want %v", sim.Makespan, tt.wantMakespan)
+			}
+			if len(sim.Tasks) != len(buildTasks) {
+				t.Fatalf("SimulateSchedule() scheduled %d tasks, want %d", len(sim.Tasks), len(buildTasks))
+			}
+
+			finish := make(map[string]time.Duration)
+			for _, st := range sim.Tasks {
+				finish[st.Name] = st.Finish
+			}
+			for _, st := range sim.Tasks {
+				for _, dep := range buildTasks[st.Name] {
+					if finish[dep] > st.Start {
+						t.Errorf("SimulateSchedule() %v starts at %v before %v finishes at %v", st.Name, st.Start, dep, finish[dep])
+					}
+				}
+				for _, other := range sim.Tasks {
+					if other.Name != st.Name && other.Worker == st.Worker && other.Start < st.Finish && st.Start < other.Finish {
+						t.Errorf("SimulateSchedule() %v and %v overlap on worker %d", st.Name, other.Name, st.Worker)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSimulateSchedulePrefersCriticalTasks(t *testing.T) {
+	tasks := map[string][]string{
+		"a_short": {},
+		"z_long":  {},
+		"after":   {"z_long"},
+	}
+	durations := map[string]time.Duration{
+		"a_short": 5 * time.Second,
+		"z_long":  5 * time.Second,
+		"after":   10 * time.Second,
+	}
+
+	sim, err := SimulateSchedule(tasks, durations, 1)
+	if err != nil {
+		t.Fatalf("SimulateSchedule() unexpected error = %v", err)
+	}
+	if sim.Tasks[0].Name != "z_long" {
+		t.Errorf("SimulateSchedule() first task = %v, want z_long", sim.Tasks[0].Name)
+	}
+
+	if _, err := SimulateSchedule(tasks, durations, 0); err == nil {
+		t.Errorf("SimulateSchedule() expected error for zero workers but got none")
+	}
+}
@@ -1,8 +1,21 @@
 package main
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+)
 
 func main() {
+	if len(os.Args) > 1 {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		code := runCLI(ctx, os.Args[1:], os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
+
 	tasks := map[string][]string{
 		"compile":       {"download_deps"},
 		"run_tests":     {"compile"},
@@ -11,47 +24,53 @@ func main() {
 		"download_deps": {},
 	}
 	fmt.Println(ResolveOrder(tasks))
+	fmt.Println(ResolveLevels(tasks))
 }
 
+// ResolveOrder lists the tasks so that every task comes after its
+// dependencies. It resolves the graph without recursion, so arbitrarily deep
+// dependency chains are fine.
 func ResolveOrder(tasks map[string][]string) ([]string, error) {
-	visited := make(map[string]bool)
-	onPath := make(map[string]bool)
-	var result []string
-	var visit func(task string) error
-	visit = func(task string) error {
-		if onPath[task] {
-			return fmt.Errorf("cycle detected on filed %v", task)
-		}
-		if visited[task] {
-			return nil
-		}
+	g, err := NewGraph(tasks)
+	if err != nil {
+		return nil, err
+	}
+	return g.Order()
+}
 
-		onPath[task] = true
+func ResolveLevels(tasks map[string][]string) ([][]string, error) {
+	order, err := ResolveOrder(tasks)
+	if err != nil {
+		return nil, err
+	}
 
-		for _, t := range tasks[task] {
-			if _, ok := tasks[t]; !ok {
-				return fmt.Errorf("could not find task %v in the given tasks", t)
-			}
-			if !visited[t] {
-				if err := visit(t); err != nil {
-					return err
-				}
+	level := make(map[string]int, len(order))
+	var levels [][]string
+	for _, task := range order {
+		l := 0
+		for _, dep := range tasks[task] {
+			if level[dep]+1 > l {
+				l = level[dep] + 1
 			}
 		}
+		level[task] = l
+		if l == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[l] = append(levels[l], task)
+	}
 
-		visited[task] = true
-		onPath[task] = false
-		result = append(result, task)
-		return nil
+	for _, l := range levels {
+		sort.Strings(l)
 	}
+	return levels, nil
+}
 
+func sortedTasks(tasks map[string][]string) []string {
+	names := make([]string, 0, len(tasks))
 	for task := range tasks {
-		if !visited[task] {
-			if err := visit(task); err != nil {
-				return nil, err
-			}
-		}
+		names = append(names, task)
 	}
-
-	return result, nil
+	sort.Strings(names)
+	return names
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -187,5 +188,94 @@ func TestResolveOrderDeterministic(t *testing.T) {
 		if !isValidTopologicalOrder(tasks, results[i]) {
 			t.Errorf("ResolveOrder() run %d produced invalid order: %v", i, results[i])
 		}
+		if !reflect.DeepEqual(results[i], results[0]) {
+			t.Errorf("ResolveOrder() run %d = %v, want %v", i, results[i], results[0])
+		}
+	}
+}
+
+func TestResolveLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		tasks   map[string][]string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name: "basic dependency resolution",
+			tasks: map[string][]string{
+				"compile":       {"download_deps"},
+				"run_tests":     {"compile"},
+				"create_image":  {"compile"},
+				"deploy":        {"run_tests", "create_image"},
+				"download_deps": {},
+			},
+			want: [][]string{
+				{"download_deps"},
+				{"compile"},
+				{"create_image", "run_tests"},
+				{"deploy"},
+			},
+		},
+		{
+			name: "no dependencies",
+			tasks: map[string][]string{
+				"task3": {},
+				"task1": {},
+				"task2": {},
+			},
+			want: [][]string{{"task1", "task2", "task3"}},
+		},
+		{
+			name: "task placed after its deepest dependency",
+			tasks: map[string][]string{
+				"a": {},
+				"b": {"a"},
+				"c": {"b"},
+				"d": {"a", "c"},
+				"e": {},
+			},
+			want: [][]string{{"a", "e"}, {"b"}, {"c"}, {"d"}},
+		},
+		{
+			name:  "empty tasks",
+			tasks: map[string][]string{},
+			want:  nil,
+		},
+		{
+			name: "cycle",
+			tasks: map[string][]string{
+				"task1": {"task2"},
+				"task2": {"task1"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing dependency",
+			tasks: map[string][]string{
+				"task1": {"task2"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				got, err := ResolveLevels(tt.tasks)
+				if tt.wantErr {
+					if err == nil {
+						t.Fatalf("ResolveLevels() expected error but got none")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ResolveLevels() unexpected error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("ResolveLevels() = %v, want %v", got, tt.want)
+				}
+			}
+		})
 	}
 }
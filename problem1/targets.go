@@ -0,0 +1,59 @@
+package main
+
+import "fmt"
+
+type UnknownTaskError struct {
+	Task string
+}
+
+func (e *UnknownTaskError) Error() string {
+	return fmt.Sprintf("could not find task %v in the given tasks", e.Task)
+}
+
+func ResolveTargets(tasks map[string][]string, targets []string, exclude []string) ([]string, error) {
+	sub, err := Subgraph(tasks, targets, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveOrder(sub)
+}
+
+func Subgraph(tasks map[string][]string, targets []string, exclude []string) (map[string][]string, error) {
+	excluded := make(map[string]bool, len(exclude))
+	for _, task := range exclude {
+		excluded[task] = true
+	}
+
+	sub := make(map[string][]string)
+	var stack []string
+	for _, target := range targets {
+		if _, ok := tasks[target]; !ok {
+			return nil, &UnknownTaskError{Task: target}
+		}
+		if !excluded[target] {
+			stack = append(stack, target)
+		}
+	}
+
+	for len(stack) > 0 {
+		task := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, seen := sub[task]; seen {
+			continue
+		}
+
+		deps := []string{}
+		for _, dep := range tasks[task] {
+			if excluded[dep] {
+				continue
+			}
+			if _, ok := tasks[dep]; !ok {
+				return nil, &MissingDependencyError{Task: task, Dependency: dep}
+			}
+			deps = append(deps, dep)
+			stack = append(stack, dep)
+		}
+		sub[task] = deps
+	}
+	return sub, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveTargets(t *testing.T) {
+	tasks := map[string][]string{
+		"download":   {},
+		"compile":    {"download"},
+		"lint":       {},
+		"test":       {"compile"},
+		"build":      {"compile"},
+		"package":    {"build"},
+		"deploy":     {"test", "package"},
+		"docs":       {"lint"},
+		"distribute": {"package", "docs"},
+	}
+
+	tests := []struct {
+		name    string
+		targets []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name:    "single target",
+			targets: []string{"build"},
+			want:    []string{"download", "compile", "build"},
+		},
+		{
+			name:    "target without dependencies",
+			targets: []string{"lint"},
+			want:    []string{"lint"},
+		},
+		{
+			name:    "multiple targets share dependencies",
+			targets: []string{"test", "docs"},
+			want:    []string{"download", "compile", "lint", "docs", "test"},
+		},
+		{
+			name:    "excluded tasks are pruned with their dependencies",
+			targets: []string{"deploy"},
+			exclude: []string{"compile"},
+			want:    []string{"build", "test", "package", "deploy"},
+		},
+		{
+			name:    "excluded dependency still needed elsewhere",
+			targets: []string{"distribute"},
+			exclude: []string{"package"},
+			want:    []string{"lint", "docs", "distribute"},
+		},
+		{
+			name:    "excluded target",
+			targets: []string{"deploy"},
+			exclude: []string{"deploy"},
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveTargets(tasks, tt.targets, tt.exclude)
+			if err != nil {
+				t.Fatalf("ResolveTargets() unexpected error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTargetsErrors(t *testing.T) {
+	tasks := map[string][]string{
+		"deploy":  {"build"},
+		"build":   {"fetch"},
+		"release": {"loop"},
+		"loop":    {"release"},
+		"ok":      {},
+	}
+
+	_, err := ResolveTargets(tasks, []string{"nope"}, nil)
+	var unknownErr *UnknownTaskError
+	if !errors.As(err, &unknownErr) || unknownErr.Task != "nope" {
+		t.Errorf("ResolveTargets() error = %v, want *UnknownTaskError for nope", err)
+	}
+
+	_, err = ResolveTargets(tasks, []string{"deploy"}, nil)
+	var missingErr *MissingDependencyError
+	if !errors.As(err, &missingErr) || missingErr.Dependency != "fetch" {
+		t.Errorf("ResolveTargets() error = %v, want *MissingDependencyError for fetch", err)
+	}
+
+	_, err = ResolveTargets(tasks, []string{"release"}, nil)
+	var cycleErr *CycleError
+	if !errors.As(err, &cycleErr) {
+		t.Errorf("ResolveTargets() error = %v, want *CycleError", err)
+	}
+
+	if got, err := ResolveTargets(tasks, []string{"ok"}, nil); err != nil || !reflect.DeepEqual(got, []string{"ok"}) {
+		t.Errorf("ResolveTargets() = %v, %v, want [ok] ignoring unrelated broken tasks", got, err)
+	}
+}
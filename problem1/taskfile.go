@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type TaskDef struct {
+	Name        string
+	Description string
+	Deps        []string
+	Commands    []string
+	Line        int
+}
+
+type TaskFile struct {
+	Tasks map[string]*TaskDef
+}
+
+type ParseError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func LoadTaskFile(path string) (*TaskFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open task file: %w", err)
+	}
+	defer f.Close()
+
+	tf, err := ParseTaskFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return tf, nil
+}
+
+func ParseTaskFile(r io.Reader) (*TaskFile, error) {
+	type depRef struct {
+		task, dep string
+		line      int
+	}
+
+	tf := &TaskFile{Tasks: make(map[string]*TaskDef)}
+	var current *TaskDef
+	var refs []depRef
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if !strings.HasSuffix(text, "]") {
+				return nil, &ParseError{Line: line, Msg: "malformed task header: missing closing ']'"}
+			}
+			name := strings.TrimSpace(text[1 : len(text)-1])
+			if name == "" || strings.ContainsAny(name, " \t,[]") {
+				return nil, &ParseError{Line: line, Msg: fmt.Sprintf("invalid task name %q", name)}
+			}
+			if prev, ok := tf.Tasks[name]; ok {
+				return nil, &ParseError{Line: line, Msg: fmt.Sprintf("task %q already defined on line %d", name, prev.Line)}
+			}
+			current = &TaskDef{Name: name, Deps: []string{}, Line: line}
+			tf.Tasks[name] = current
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, &ParseError{Line: line, Msg: fmt.Sprintf("expected 'key = value', got %q", text)}
+		}
+		if current == nil {
+			return nil, &ParseError{Line: line, Msg: "property outside of a task section"}
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch key {
+		case "deps":
+			for _, dep := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+				current.Deps = append(current.Deps, dep)
+				refs = append(refs, depRef{task: current.Name, dep: dep, line: line})
+			}
+		case "run":
+			if value == "" {
+				return nil, &ParseError{Line: line, Msg: "empty run command"}
+			}
+			current.Commands = append(current.Commands, value)
+		case "description", "desc":
+			if current.Description != "" {
+				return nil, &ParseError{Line: line, Msg: fmt.Sprintf("duplicate description for task %q", current.Name)}
+			}
+			current.Description = value
+		default:
+			return nil, &ParseError{Line: line, Msg: fmt.Sprintf("unknown key %q", key)}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read task file: %w", err)
+	}
+
+	for _, ref := range refs {
+		if _, ok := tf.Tasks[ref.dep]; !ok {
+			return nil, &ParseError{Line: ref.line, Msg: fmt.Sprintf("task %q depends on undefined task %q", ref.task, ref.dep)}
+		}
+	}
+	return tf, nil
+}
+
+func (tf *TaskFile) Graph() map[string][]string {
+	graph := make(map[string][]string, len(tf.Tasks))
+	for name, def := range tf.Tasks {
+		graph[name] = def.Deps
+	}
+	return graph
+}
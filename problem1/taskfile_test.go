@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleTaskFile = `# build pipeline
+[download_deps]
+description = Fetch modules
+run = go mod download
+
+[compile]
+deps = download_deps
+run = go build ./...
+run = go vet ./...
+
+[run_tests]
+desc = Run the unit tests
+deps = compile
+run = go test ./...
+
+[deploy]
+deps = run_tests, compile
+`
+
+func TestParseTaskFile(t *testing.T) {
+	tf, err := ParseTaskFile(strings.NewReader(sampleTaskFile))
+	if err != nil {
+		t.Fatalf("ParseTaskFile() unexpected error = %v", err)
+	}
+
+	want := map[string]*TaskDef{
+		"download_deps": {Name: "download_deps", Description: "Fetch modules", Deps: []string{}, Commands: []string{"go mod download"}, Line: 2},
+		"compile":       {Name: "compile", Deps: []string{"download_deps"}, Commands: []string{"go build ./...", "go vet ./..."}, Line: 6},
+		"run_tests":     {Name: "run_tests", Description: "Run the unit tests", Deps: []string{"compile"}, Commands: []string{"go test ./..."}, Line: 11},
+		"deploy":        {Name: "deploy", Deps: []string{"run_tests", "compile"}, Line: 16},
+	}
+	if !reflect.DeepEqual(tf.Tasks, want) {
+		for name, def := range tf.Tasks {
+			t.Logf("%v: %+v", name, *def)
+		}
+		t.Errorf("ParseTaskFile() tasks differ from expected")
+	}
+
+	order, err := ResolveOrder(tf.Graph())
+	if err != nil {
+		t.Fatalf("ResolveOrder() unexpected error = %v", err)
+	}
+	if want := []string{"download_deps", "compile", "run_tests", "deploy"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("ResolveOrder() = %v, want %v", order, want)
+	}
+}
+
+func TestParseTaskFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantLine int
+		errMsg   string
+	}{
+		{"property outside section", "run = echo hi\n", 1, "outside of a task section"},
+		{"missing equals", "[a]\nrun echo hi\n", 2, "expected 'key = value'"},
+		{"unclosed header", "[a]\n\n[b\n", 3, "missing closing ']'"},
+		{"empty task name", "[ ]\n", 1, "invalid task name"},
+		{"duplicate task", "[a]\n[b]\n[a]\n", 3, "already defined on line 1"},
+		{"unknown key", "[a]\nafter = b\n", 2, "unknown key"},
+		{"empty command", "[a]\nrun =\n", 2, "empty run command"},
+		{"undefined dependency", "[a]\ndeps = b\n\n[b]\ndeps = a, c\n", 5, "undefined task \"c\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTaskFile(strings.NewReader(tt.input))
+
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("ParseTaskFile() error = %v, want *ParseError", err)
+			}
+			if parseErr.Line != tt.wantLine {
+				t.Errorf("ParseTaskFile() error line = %d, want %d", parseErr.Line, tt.wantLine)
+			}
+			if !contains(err.Error(), tt.errMsg) {
+				t.Errorf("ParseTaskFile() error = %v, want error containing %v", err, tt.errMsg)
+			}
+		})
+	}
+}
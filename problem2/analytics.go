@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"time"
+)
+
+type Sale struct {
+	Time     time.Time `json:"time"`
+	Product  string    `json:"product"`
+	Quantity int       `json:"quantity"`
+	Revenue  int       `json:"revenue"`
+	Payment  string    `json:"payment,omitempty"`
+	Session  string    `json:"session,omitempty"`
+	// Unpaid marks a sale whose payment failed after the goods were
+	// dispensed. It keeps its units but earns no revenue.
+	Unpaid bool `json:"unpaid,omitempty"`
+}
+
+type ProductSales struct {
+	Product string `json:"product"`
+	Units   int    `json:"units"`
+	Revenue int    `json:"revenue"`
+}
+
+type Holdings struct {
+	Coins     Change `json:"coins"`
+	Banknotes Change `json:"banknotes"`
+	Credit    int    `json:"credit"`
+}
+
+// Total is the cash in the machine, including credit that customers have
+// inserted but not spent yet.
+func (h Holdings) Total() int {
+	return h.Coins.Total() + h.Banknotes.Total()
+}
+
+// Sales returns every sale in [from, to). A zero from or to leaves that end of
+// the range open.
+func (v *VendingMachine) Sales(from, to time.Time) []Sale {
+	v.Lock()
+	defer v.Unlock()
+	var sales []Sale
+	for _, s := range v.sales {
+		if (from.IsZero() || !s.Time.Before(from)) && (to.IsZero() || s.Time.Before(to)) {
+			sales = append(sales, s)
+		}
+	}
+	return sales
+}
+
+func (v *VendingMachine) SalesReport(from, to time.Time) []ProductSales {
+	totals := make(map[string]*ProductSales)
+	for _, s := range v.Sales(from, to) {
+		ps, ok := totals[s.Product]
+		if !ok {
+			ps = &ProductSales{Product: s.Product}
+			totals[s.Product] = ps
+		}
+		ps.Units += s.Quantity
+		ps.Revenue += s.Revenue
+	}
+
+	report := make([]ProductSales, 0, len(totals))
+	for _, ps := range totals {
+		report = append(report, *ps)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Product < report[j].Product })
+	return report
+}
+
+func (v *VendingMachine) TopSellers(from, to time.Time, n int) []ProductSales {
+	report := v.SalesReport(from, to)
+	sort.SliceStable(report, func(i, j int) bool {
+		if report[i].Units != report[j].Units {
+			return report[i].Units > report[j].Units
+		}
+		return report[i].Revenue > report[j].Revenue
+	})
+	if n >= 0 && n < len(report) {
+		report = report[:n]
+	}
+	return report
+}
+
+func (v *VendingMachine) MoneyHeld() Holdings {
+	v.Lock()
+	defer v.Unlock()
+	h := Holdings{Coins: maps.Clone(v.Coins), Banknotes: maps.Clone(v.Banknotes)}
+	for _, sess := range v.sessions {
+		h.Credit += sess.balance
+		for coin, count := range sess.inserted {
+			h.Coins[coin] += count
+		}
+		for note, count := range sess.notes {
+			h.Banknotes[note] += count
+		}
+	}
+	return h
+}
+
+// SetLowStockAlert calls fn whenever a sale or a disabled slot takes a
+// product's stock from threshold or above to below it. Like listeners, fn
+// runs while the machine is locked. A nil fn disables the alert.
+func (v *VendingMachine) SetLowStockAlert(threshold int, fn func(Item)) {
+	v.Lock()
+	defer v.Unlock()
+	v.lowStockThreshold = threshold
+	v.lowStock = fn
+}
+
+// recordSale splits the amount paid over the products of a sale in proportion
+// to their list prices, giving any rounding remainder to the last product.
+func (v *VendingMachine) recordSale(e Event, items map[string]int, prices map[string]int) {
+	names := make([]string, 0, len(items))
+	listTotal := 0
+	for name, quantity := range items {
+		names = append(names, name)
+		listTotal += prices[name] * quantity
+	}
+	sort.Strings(names)
+
+	remaining := e.Amount
+	for i, name := range names {
+		revenue := remaining
+		if i < len(names)-1 && listTotal > 0 {
+			revenue = e.Amount * prices[name] * items[name] / listTotal
+		}
+		remaining -= revenue
+		v.sales = append(v.sales, Sale{
+			Time:     e.Time,
+			Product:  name,
+			Quantity: items[name],
+			Revenue:  revenue,
+			Payment:  e.Payment,
+			Session:  e.Session,
+		})
+	}
+}
+
+// markUnpaid takes the revenue away from the latest sale of e.Items that the
+// session paid for with e.Payment.
+func (v *VendingMachine) markUnpaid(e Event) error {
+	remaining := maps.Clone(e.Items)
+	for i := len(v.sales) - 1; i >= 0 && len(remaining) > 0; i-- {
+		s := &v.sales[i]
+		if s.Unpaid || s.Session != e.Session || s.Payment != e.Payment || remaining[s.Product] != s.Quantity {
+			continue
+		}
+		s.Revenue = 0
+		s.Unpaid = true
+		delete(remaining, s.Product)
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("no %v sale of %v to mark unpaid", e.Payment, remaining)
+	}
+	return nil
+}
+
+func (v *VendingMachine) checkLowStock(name string, before int) {
+	if v.lowStock == nil {
+		return
+	}
+	item := v.Inventory[name]
+	if before >= v.lowStockThreshold && item.Stock < v.lowStockThreshold {
+		v.lowStock(item)
+	}
+}
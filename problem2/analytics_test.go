@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSalesReport(t *testing.T) {
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	vm := newPricedMachine(t, now, ComboRule{Products: []string{"Chips", "Cola"}, Price: 50})
+	vm.SetClock(func() time.Time { return now })
+
+	insertCredit(t, vm, 10)
+	vm.SelectProduct("Candy")
+	now = now.Add(time.Hour)
+	insertCredit(t, vm, 50)
+	vm.Buy(map[string]int{"Chips": 1, "Cola": 1})
+	vm.BuyWith(NewMockCardTerminal(100), map[string]int{"Candy": 3})
+	now = now.Add(time.Hour)
+	insertCredit(t, vm, 25)
+	vm.SelectProduct("Cola")
+
+	start := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []ProductSales
+	}{
+		{
+			name: "all time",
+			want: []ProductSales{
+				{Product: "Candy", Units: 4, Revenue: 40},
+				{Product: "Chips", Units: 1, Revenue: 29},
+				{Product: "Cola", Units: 2, Revenue: 46},
+			},
+		},
+		{
+			name: "second hour only",
+			from: start.Add(time.Hour),
+			to:   start.Add(2 * time.Hour),
+			want: []ProductSales{
+				{Product: "Candy", Units: 3, Revenue: 30},
+				{Product: "Chips", Units: 1, Revenue: 29},
+				{Product: "Cola", Units: 1, Revenue: 21},
+			},
+		},
+		{
+			name: "open ended",
+			from: start.Add(2 * time.Hour),
+			want: []ProductSales{{Product: "Cola", Units: 1, Revenue: 25}},
+		},
+		{
+			name: "empty range",
+			to:   start,
+			want: []ProductSales{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vm.SalesReport(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SalesReport() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	top := vm.TopSellers(time.Time{}, time.Time{}, 2)
+	if want := []ProductSales{{Product: "Candy", Units: 4, Revenue: 40}, {Product: "Cola", Units: 2, Revenue: 46}}; !reflect.DeepEqual(top, want) {
+		t.Errorf("TopSellers() = %v, want %v", top, want)
+	}
+	if sales := vm.Sales(time.Time{}, time.Time{}); sales[2].Payment != "" || sales[3].Payment != "card" {
+		t.Errorf("Sales() payments = %q, %q, want cash then card", sales[2].Payment, sales[3].Payment)
+	}
+}
+
+func TestSalesSurviveReplay(t *testing.T) {
+	store := &MemoryEventStore{}
+	vm := newPricedMachine(t, time.Now())
+	vm.UseEventStore(store)
+	insertCredit(t, vm, 35)
+	vm.SelectProduct("Chips")
+
+	events, _ := store.Events()
+	replayed, err := Replay(map[string]Item{"Chips": {Name: "Chips", Price: 35, Stock: 10}}, events)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(replayed.Sales(time.Time{}, time.Time{}), vm.Sales(time.Time{}, time.Time{})) {
+		t.Errorf("Replay() sales = %v, want %v", replayed.Sales(time.Time{}, time.Time{}), vm.Sales(time.Time{}, time.Time{}))
+	}
+}
+
+func TestSnapshotLeavesSalesOut(t *testing.T) {
+	vm := newPricedMachine(t, time.Now())
+	var sizes []int
+	for i := 0; i < 3; i++ {
+		insertCredit(t, vm, 10)
+		vm.SelectProduct("Candy")
+		var buf bytes.Buffer
+		vm.Save(&buf)
+		sizes = append(sizes, buf.Len())
+	}
+	if sizes[0] != sizes[1] || sizes[1] != sizes[2] {
+		t.Errorf("Save() sizes = %v, want them not to grow with every sale", sizes)
+	}
+
+	var buf bytes.Buffer
+	vm.Save(&buf)
+	if err := vm.Load(&buf); err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if got := vm.SalesReport(time.Time{}, time.Time{}); !reflect.DeepEqual(got, []ProductSales{{Product: "Candy", Units: 3, Revenue: 30}}) {
+		t.Errorf("Load() sales report = %v, want the history kept", got)
+	}
+}
+
+func TestMoneyHeld(t *testing.T) {
+	vm := newPricedMachine(t, time.Now())
+	vm.InsertBanknote(100)
+	sess, _ := vm.StartSession()
+	sess.InsertCoin(25)
+
+	held := vm.MoneyHeld()
+	want := Holdings{
+		Coins:     Change{1: 10, 5: 10, 10: 10, 25: 1},
+		Banknotes: Change{100: 1},
+		Credit:    125,
+	}
+	if !reflect.DeepEqual(held, want) {
+		t.Errorf("MoneyHeld() = %+v, want %+v", held, want)
+	}
+	if held.Total() != 285 {
+		t.Errorf("MoneyHeld().Total() = %v, want 285", held.Total())
+	}
+}
+
+func TestLowStockAlert(t *testing.T) {
+	vm := newPricedMachine(t, time.Now())
+	var alerts []Item
+	vm.SetLowStockAlert(8, func(item Item) { alerts = append(alerts, item) })
+
+	for i := 0; i < 4; i++ {
+		insertCredit(t, vm, 25)
+		vm.SelectProduct("Cola")
+	}
+	vm.BuyWith(NewMockCardTerminal(1000), map[string]int{"Chips": 5, "Candy": 1})
+
+	want := []Item{
+		{Name: "Cola", Price: 25, Stock: 7},
+		{Name: "Chips", Price: 35, Stock: 5},
+	}
+	if !reflect.DeepEqual(alerts, want) {
+		t.Errorf("low stock alerts = %v, want %v", alerts, want)
+	}
+
+	vm.SetLowStockAlert(0, nil)
+	insertCredit(t, vm, 25)
+	if _, _, err := vm.SelectProduct("Cola"); err != nil {
+		t.Errorf("SelectProduct() unexpected error = %v with the alert disabled", err)
+	}
+}
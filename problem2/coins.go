@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrNoExactChange = errors.New("cannot make exact change")
+
+type Change map[int]int
+
+func (c Change) Total() int {
+	total := 0
+	for coin, count := range c {
+		total += coin * count
+	}
+	return total
+}
+
+func (c Change) String() string {
+	coins := make([]int, 0, len(c))
+	for coin, count := range c {
+		if count > 0 {
+			coins = append(coins, coin)
+		}
+	}
+	if len(coins) == 0 {
+		return "0"
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(coins)))
+
+	parts := make([]string, len(coins))
+	for i, coin := range coins {
+		parts[i] = fmt.Sprintf("%dx%d", c[coin], coin)
+	}
+	return fmt.Sprintf("%d (%s)", c.Total(), strings.Join(parts, " + "))
+}
+
+func (v *VendingMachine) LoadCoins(cred Credentials, coins map[int]int) error {
+	v.Lock()
+	defer v.Unlock()
+	loaded := Change{}
+	for coin, count := range coins {
+		if count > 0 {
+			loaded[coin] = count
+		}
+	}
+	e := Event{Type: EventLoadCoins, Operator: cred.ID, Coins: loaded}
+	if err := v.authorize(cred, PermManageCash); err != nil {
+		return v.reject(e, err)
+	}
+	for coin, count := range coins {
+		if !v.coinValues[coin] {
+			return v.reject(e, fmt.Errorf("%w: %d", ErrInvalidCoin, coin))
+		}
+		if count < 0 {
+			return v.reject(e, fmt.Errorf("%w: cannot load %d coins of %d", ErrInvalidArgument, count, coin))
+		}
+	}
+	return v.commit(e)
+}
+
+func (v *VendingMachine) EmptyCoins(cred Credentials, coins ...int) (Change, error) {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventEmptyCoins, Operator: cred.ID}
+	if err := v.authorize(cred, PermManageCash); err != nil {
+		return nil, v.reject(e, err)
+	}
+	if len(coins) == 0 {
+		for coin := range v.Coins {
+			coins = append(coins, coin)
+		}
+	}
+
+	removed := Change{}
+	for _, coin := range coins {
+		if v.Coins[coin] > 0 {
+			removed[coin] = v.Coins[coin]
+		}
+	}
+	e.Coins = removed
+	v.settle(&e)
+	return removed, nil
+}
+
+func (v *VendingMachine) CoinStock() Change {
+	v.Lock()
+	defer v.Unlock()
+	stock := Change{}
+	for coin, count := range v.Coins {
+		if count > 0 {
+			stock[coin] = count
+		}
+	}
+	return stock
+}
+
+func makeChange(amount int, stock map[int]int) (Change, bool) {
+	coins := make([]int, 0, len(stock))
+	for coin, count := range stock {
+		if count > 0 {
+			coins = append(coins, coin)
+		}
+	}
+	sort.Ints(coins)
+
+	const unreachable = -1
+	best := make([]int, amount+1)
+	for a := 1; a <= amount; a++ {
+		best[a] = unreachable
+	}
+
+	choices := make([][]int, len(coins))
+	for i, coin := range coins {
+		next := make([]int, amount+1)
+		choice := make([]int, amount+1)
+		for a := 0; a <= amount; a++ {
+			next[a] = unreachable
+			for k := 0; k <= stock[coin] && k*coin <= a; k++ {
+				prev := best[a-k*coin]
+				if prev == unreachable {
+					continue
+				}
+				if next[a] == unreachable || prev+k < next[a] {
+					next[a] = prev + k
+					choice[a] = k
+				}
+			}
+		}
+		best = next
+		choices[i] = choice
+	}
+
+	if best[amount] == unreachable {
+		return nil, false
+	}
+	change := Change{}
+	for i, a := len(coins)-1, amount; i >= 0; i-- {
+		if k := choices[i][a]; k > 0 {
+			change[coins[i]] = k
+			a -= k * coins[i]
+		}
+	}
+	return change, true
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMakeChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int
+		stock  map[int]int
+		want   Change
+		wantOK bool
+	}{
+		{"zero amount", 0, map[int]int{}, Change{}, true},
+		{"greedy", 41, map[int]int{1: 5, 5: 5, 10: 5, 25: 5}, Change{25: 1, 10: 1, 5: 1, 1: 1}, true},
+		{"greedy would fail", 30, map[int]int{25: 1, 10: 3}, Change{10: 3}, true},
+		{"limited coins", 15, map[int]int{10: 0, 5: 2, 1: 10}, Change{5: 2, 1: 5}, true},
+		{"not enough coins", 15, map[int]int{10: 1, 1: 4}, nil, false},
+		{"empty stock", 5, map[int]int{}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := makeChange(tt.amount, tt.stock)
+			if ok != tt.wantOK {
+				t.Fatalf("makeChange() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeChange() = %v, want %v", got, tt.want)
+			}
+			if ok && got.Total() != tt.amount {
+				t.Errorf("makeChange() total = %v, want %v", got.Total(), tt.amount)
+			}
+		})
+	}
+}
+
+// loadCoins loads coins as the admin, registering the admin first if the
+// machine has no operators yet.
+func loadCoins(t *testing.T, vm *VendingMachine, coins map[int]int) {
+	t.Helper()
+	if len(vm.operators) == 0 {
+		if err := vm.RegisterOperator(Credentials{}, admin.ID, admin.PIN, RoleAdmin); err != nil {
+			t.Fatalf("RegisterOperator() unexpected error = %v", err)
+		}
+	}
+	if err := vm.LoadCoins(admin, coins); err != nil {
+		t.Fatalf("LoadCoins() unexpected error = %v", err)
+	}
+}
+
+func TestSelectProductPaysChangeFromStock(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Chips": {Name: "Chips", Price: 35, Stock: 10}})
+	loadCoins(t, vm, map[int]int{10: 3, 5: 0, 1: 5})
+
+	vm.InsertCoin(25)
+	vm.InsertCoin(25)
+	change, _, err := vm.SelectProduct("Chips")
+	if err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+	if want := (Change{10: 1, 1: 5}); !reflect.DeepEqual(change, want) {
+		t.Errorf("SelectProduct() change = %v, want %v", change, want)
+	}
+	if want := (Change{25: 2, 10: 2}); !reflect.DeepEqual(vm.CoinStock(), want) {
+		t.Errorf("SelectProduct() coin stock = %v, want %v", vm.CoinStock(), want)
+	}
+}
+
+func TestSelectProductUsesInsertedCoinsForChange(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Cola": {Name: "Cola", Price: 25, Stock: 1}})
+
+	for _, coin := range []int{5, 5, 25} {
+		vm.InsertCoin(coin)
+	}
+	change, _, err := vm.SelectProduct("Cola")
+	if err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+	if want := (Change{5: 2}); !reflect.DeepEqual(change, want) {
+		t.Errorf("SelectProduct() change = %v, want %v", change, want)
+	}
+	if want := (Change{25: 1}); !reflect.DeepEqual(vm.CoinStock(), want) {
+		t.Errorf("SelectProduct() coin stock = %v, want %v", vm.CoinStock(), want)
+	}
+}
+
+func TestSelectProductRefusesWithoutExactChange(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Chips": {Name: "Chips", Price: 35, Stock: 10}})
+	loadCoins(t, vm, map[int]int{10: 1})
+
+	vm.InsertCoin(25)
+	vm.InsertCoin(25)
+	_, _, err := vm.SelectProduct("Chips")
+	if !errors.Is(err, ErrNoExactChange) {
+		t.Fatalf("SelectProduct() error = %v, want %v", err, ErrNoExactChange)
+	}
+	if vm.Balance() != 50 {
+		t.Errorf("SelectProduct() balance after refusal = %v, want 50", vm.Balance())
+	}
+	if vm.Inventory["Chips"].Stock != 10 {
+		t.Errorf("SelectProduct() stock after refusal = %v, want 10", vm.Inventory["Chips"].Stock)
+	}
+	if want := (Change{10: 1}); !reflect.DeepEqual(vm.CoinStock(), want) {
+		t.Errorf("SelectProduct() coin stock after refusal = %v, want %v", vm.CoinStock(), want)
+	}
+
+	if refund := vm.Cancel(); refund != 50 {
+		t.Errorf("Cancel() refund = %v, want 50", refund)
+	}
+	if want := (Change{10: 1}); !reflect.DeepEqual(vm.CoinStock(), want) {
+		t.Errorf("Cancel() coin stock = %v, want %v", vm.CoinStock(), want)
+	}
+}
+
+func TestLoadAndEmptyCoins(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{})
+	registerOperator(t, vm, manager, RoleManager)
+
+	if err := vm.LoadCoins(manager, map[int]int{25: 10, 5: 20}); err != nil {
+		t.Fatalf("LoadCoins() unexpected error = %v", err)
+	}
+	if err := vm.LoadCoins(manager, map[int]int{25: 2}); err != nil {
+		t.Fatalf("LoadCoins() unexpected error = %v", err)
+	}
+	if err := vm.LoadCoins(manager, map[int]int{3: 1}); err == nil {
+		t.Errorf("LoadCoins() expected error for invalid coin but got none")
+	}
+	if err := vm.LoadCoins(manager, map[int]int{5: -1}); err == nil {
+		t.Errorf("LoadCoins() expected error for negative count but got none")
+	}
+	if err := vm.LoadCoins(Credentials{}, map[int]int{5: 1}); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("LoadCoins() without credentials error = %v, want ErrUnauthorized", err)
+	}
+	if want := (Change{25: 12, 5: 20}); !reflect.DeepEqual(vm.CoinStock(), want) {
+		t.Errorf("LoadCoins() coin stock = %v, want %v", vm.CoinStock(), want)
+	}
+
+	if _, err := vm.EmptyCoins(Credentials{}); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("EmptyCoins() without credentials error = %v, want ErrUnauthorized", err)
+	}
+	if got, err := vm.EmptyCoins(manager, 25); err != nil || !reflect.DeepEqual(got, Change{25: 12}) {
+		t.Errorf("EmptyCoins(25) = %v, %v, want %v", got, err, Change{25: 12})
+	}
+	if got, err := vm.EmptyCoins(manager); err != nil || !reflect.DeepEqual(got, Change{5: 20}) {
+		t.Errorf("EmptyCoins() = %v, %v, want %v", got, err, Change{5: 20})
+	}
+	if len(vm.CoinStock()) != 0 {
+		t.Errorf("EmptyCoins() left %v in stock", vm.CoinStock())
+	}
+}
+
+func TestChangeString(t *testing.T) {
+	if got := (Change{25: 1, 5: 2}).String(); got != "35 (1x25 + 2x5)" {
+		t.Errorf("Change.String() = %v, want %v", got, "35 (1x25 + 2x5)")
+	}
+	if got := (Change{}).String(); got != "0" {
+		t.Errorf("Change.String() = %v, want 0", got)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventInsertCoin       EventType = "insert_coin"
+	EventInsertBanknote   EventType = "insert_banknote"
+	EventSelectProduct    EventType = "select_product"
+	EventCancel           EventType = "cancel"
+	EventLoadCoins        EventType = "load_coins"
+	EventEmptyCoins       EventType = "empty_coins"
+	EventEnterMaintenance EventType = "enter_maintenance"
+	EventExitMaintenance  EventType = "exit_maintenance"
+	EventAddProduct       EventType = "add_product"
+	EventRemoveProduct    EventType = "remove_product"
+	EventRestock          EventType = "restock"
+	EventSetPrice         EventType = "set_price"
+	EventStartSession     EventType = "start_session"
+	EventEndSession       EventType = "end_session"
+	EventExpireSession    EventType = "expire_session"
+	EventSetPricingRules  EventType = "set_pricing_rules"
+	EventAssignSlot       EventType = "assign_slot"
+	EventDisableSlot      EventType = "disable_slot"
+	EventEnableSlot       EventType = "enable_slot"
+	EventPaymentFailed    EventType = "payment_failed"
+)
+
+const ResultOK = "ok"
+
+type Event struct {
+	Time     time.Time      `json:"time"`
+	Type     EventType      `json:"type"`
+	Operator string         `json:"operator,omitempty"`
+	Session  string         `json:"session,omitempty"`
+	Payment  string         `json:"payment,omitempty"`
+	Amount   int            `json:"amount,omitempty"`
+	Product  string         `json:"product,omitempty"`
+	Quantity int            `json:"quantity,omitempty"`
+	Items    map[string]int `json:"items,omitempty"`
+	Slot     string         `json:"slot,omitempty"`
+	Slots    map[string]int `json:"slots,omitempty"`
+	Coins    Change         `json:"coins,omitempty"`
+	Result   string         `json:"result"`
+}
+
+type EventStore interface {
+	Append(e Event) error
+	Events() ([]Event, error)
+}
+
+type MemoryEventStore struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *MemoryEventStore) Append(e Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *MemoryEventStore) Events() ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...), nil
+}
+
+type FileEventStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileEventStore(path string) *FileEventStore {
+	return &FileEventStore{path: path}
+}
+
+func (s *FileEventStore) Append(e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed marshalling the event: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write the event: %w", err)
+	}
+	return file.Sync()
+}
+
+func (s *FileEventStore) Events() ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer file.Close()
+	return ReadEvents(file)
+}
+
+func ReadEvents(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid event on line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	return events, nil
+}
+
+func (v *VendingMachine) UseEventStore(store EventStore) {
+	v.Lock()
+	defer v.Unlock()
+	v.events = store
+}
+
+func (v *VendingMachine) SetClock(now func() time.Time) {
+	v.Lock()
+	defer v.Unlock()
+	v.now = now
+}
+
+func Replay(inventory map[string]Item, events []Event) (*VendingMachine, error) {
+	stock := make(map[string]Item, len(inventory))
+	for name, item := range inventory {
+		stock[name] = item
+	}
+
+	vm := NewVendingMachine(stock)
+	for i, e := range events {
+		if e.Result != ResultOK {
+			continue
+		}
+		if err := vm.apply(e); err != nil {
+			return nil, fmt.Errorf("event %d (%v): %w", i, e.Type, err)
+		}
+	}
+	return vm, nil
+}
+
+func (v *VendingMachine) persist(e *Event, err error) error {
+	e.Time = v.now()
+	e.Result = ResultOK
+	if err != nil {
+		e.Result = err.Error()
+	}
+	if v.events == nil {
+		return nil
+	}
+	if err := v.events.Append(*e); err != nil {
+		return fmt.Errorf("failed to record %v event: %w", e.Type, err)
+	}
+	return nil
+}
+
+func (v *VendingMachine) commit(e Event) error {
+	if err := v.persist(&e, nil); err != nil {
+		return err
+	}
+	if err := v.apply(e); err != nil {
+		return err
+	}
+	v.autosave()
+	return nil
+}
+
+// settle applies an event that has already happened physically, such as coins
+// handed back, so it cannot be refused even when it cannot be recorded.
+func (v *VendingMachine) settle(e *Event) {
+	v.persist(e, nil)
+	v.apply(*e)
+	v.autosave()
+}
+
+func (v *VendingMachine) reject(e Event, err error) error {
+	v.persist(&e, err)
+	return err
+}
+
+func (v *VendingMachine) apply(e Event) error {
+	switch e.Type {
+	case EventStartSession:
+		if _, ok := v.sessions[e.Session]; ok {
+			return fmt.Errorf("session %v already started", e.Session)
+		}
+		v.sessions[e.Session] = newSession(e.Time)
+	case EventEndSession, EventExpireSession:
+		if _, err := v.session(e.Session); err != nil {
+			return err
+		}
+		delete(v.sessions, e.Session)
+	case EventInsertCoin:
+		sess, err := v.session(e.Session)
+		if err != nil {
+			return err
+		}
+		sess.balance += e.Amount
+		sess.inserted[e.Amount]++
+		sess.lastActive = e.Time
+	case EventInsertBanknote:
+		sess, err := v.session(e.Session)
+		if err != nil {
+			return err
+		}
+		sess.balance += e.Amount
+		sess.notes[e.Amount]++
+		sess.lastActive = e.Time
+	case EventSelectProduct:
+		sess, err := v.session(e.Session)
+		if err != nil {
+			return err
+		}
+		items := e.Items
+		if items == nil {
+			items = map[string]int{e.Product: 1}
+		}
+		for name, quantity := range items {
+			if prod, ok := v.Inventory[name]; !ok || prod.Stock < quantity {
+				return fmt.Errorf("cannot dispense %d of %v", quantity, name)
+			}
+		}
+		if err := v.moveSlotStock(e.Slots, false); err != nil {
+			return err
+		}
+		prices := make(map[string]int, len(items))
+		for name, quantity := range items {
+			prod := v.Inventory[name]
+			prices[name] = prod.Price
+			prod.Stock -= quantity
+			v.Inventory[name] = prod
+			v.checkLowStock(name, prod.Stock+quantity)
+		}
+		v.recordSale(e, items, prices)
+		sess.lastActive = e.Time
+		// Sales paid by card or another method leave the session's cash alone.
+		if e.Payment != "" {
+			break
+		}
+		for coin, count := range sess.inserted {
+			v.Coins[coin] += count
+		}
+		for note, count := range sess.notes {
+			v.Banknotes[note] += count
+		}
+		if err := v.removeCoins(e.Coins); err != nil {
+			return err
+		}
+		sess.inserted = make(map[int]int)
+		sess.notes = make(map[int]int)
+		sess.balance = 0
+	case EventCancel:
+		sess, err := v.session(e.Session)
+		if err != nil {
+			return err
+		}
+		sess.inserted = make(map[int]int)
+		sess.notes = make(map[int]int)
+		sess.balance = 0
+		sess.lastActive = e.Time
+	case EventPaymentFailed:
+		return v.markUnpaid(e)
+	case EventLoadCoins:
+		for coin, count := range e.Coins {
+			v.Coins[coin] += count
+		}
+	case EventEmptyCoins:
+		return v.removeCoins(e.Coins)
+	case EventSetPricingRules:
+		// Rules are code, not data, so only the change itself is recorded.
+	case EventEnterMaintenance:
+		v.maintenance = true
+	case EventExitMaintenance:
+		v.maintenance = false
+	case EventAddProduct:
+		if _, ok := v.Inventory[e.Product]; ok {
+			return fmt.Errorf("%w: %v", ErrProductExists, e.Product)
+		}
+		v.Inventory[e.Product] = Item{Name: e.Product, Price: e.Amount, Stock: e.Quantity}
+	case EventRemoveProduct:
+		delete(v.Inventory, e.Product)
+		for _, slot := range v.slotsFor(e.Product) {
+			delete(v.slots, slot.Code)
+		}
+	case EventRestock, EventSetPrice:
+		prod, ok := v.Inventory[e.Product]
+		if !ok {
+			return fmt.Errorf("%w : %v", ErrProductNotFound, e.Product)
+		}
+		if e.Slots != nil {
+			if err := v.moveSlotStock(e.Slots, true); err != nil {
+				return err
+			}
+			v.syncSlotStock(e.Product)
+			break
+		}
+		if e.Type == EventRestock {
+			prod.Stock += e.Quantity
+		} else {
+			prod.Price = e.Amount
+		}
+		v.Inventory[e.Product] = prod
+	case EventAssignSlot, EventDisableSlot, EventEnableSlot:
+		return v.applySlots(e)
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+	return nil
+}
+
+func (v *VendingMachine) removeCoins(coins Change) error {
+	for coin, count := range coins {
+		if v.Coins[coin] < count {
+			return fmt.Errorf("only %d coins of %d in stock, need %d", v.Coins[coin], coin, count)
+		}
+	}
+	for coin, count := range coins {
+		v.Coins[coin] -= count
+		if v.Coins[coin] == 0 {
+			delete(v.Coins, coin)
+		}
+	}
+	return nil
+}
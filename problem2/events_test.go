@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func fixedClock(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func newLoggedMachine(store EventStore) *VendingMachine {
+	vm := NewVendingMachine(map[string]Item{
+		"Cola":  {Name: "Cola", Price: 25, Stock: 5},
+		"Chips": {Name: "Chips", Price: 35, Stock: 10},
+	})
+	vm.SetClock(fixedClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))
+	vm.UseEventStore(store)
+	return vm
+}
+
+func TestEventLog(t *testing.T) {
+	store := &MemoryEventStore{}
+	vm := newLoggedMachine(store)
+
+	loadCoins(t, vm, map[int]int{10: 2, 5: 2})
+	vm.InsertCoin(25)
+	vm.InsertCoin(3)
+	vm.SelectProduct("Chips")
+	vm.InsertCoin(25)
+	vm.SelectProduct("Chips")
+	vm.InsertCoin(10)
+	vm.Cancel()
+
+	events, err := store.Events()
+	if err != nil {
+		t.Fatalf("Events() unexpected error = %v", err)
+	}
+
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	want := []Event{
+		{Type: EventLoadCoins, Operator: admin.ID, Coins: Change{10: 2, 5: 2}, Result: ResultOK},
+		{Type: EventInsertCoin, Amount: 25, Result: ResultOK},
+		{Type: EventInsertCoin, Amount: 3, Result: "not a valid coin"},
+		{Type: EventSelectProduct, Product: "Chips", Result: "insuffcient bal : 25"},
+		{Type: EventInsertCoin, Amount: 25, Result: ResultOK},
+		{Type: EventSelectProduct, Product: "Chips", Amount: 35, Coins: Change{10: 1, 5: 1}, Result: ResultOK},
+		{Type: EventInsertCoin, Amount: 10, Result: ResultOK},
+		{Type: EventCancel, Amount: 10, Result: ResultOK},
+	}
+	for i := range want {
+		want[i].Time = start.Add(time.Duration(i+1) * time.Second)
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("Events() = %+v\nwant %+v", events, want)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	store := NewFileEventStore(filepath.Join(t.TempDir(), "events.jsonl"))
+	vm := newLoggedMachine(store)
+
+	loadCoins(t, vm, map[int]int{10: 5, 5: 5, 1: 5})
+	vm.InsertCoin(25)
+	vm.InsertCoin(25)
+	vm.SelectProduct("Chips")
+	vm.InsertCoin(25)
+	vm.SelectProduct("Cola")
+	vm.InsertCoin(10)
+	vm.SelectProduct("Nope")
+	vm.EmptyCoins(admin, 1)
+	vm.InsertCoin(5)
+
+	events, err := store.Events()
+	if err != nil {
+		t.Fatalf("Events() unexpected error = %v", err)
+	}
+	replayed, err := Replay(map[string]Item{
+		"Cola":  {Name: "Cola", Price: 25, Stock: 5},
+		"Chips": {Name: "Chips", Price: 35, Stock: 10},
+	}, events)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error = %v", err)
+	}
+
+	if !reflect.DeepEqual(replayed.Inventory, vm.Inventory) {
+		t.Errorf("Replay() inventory = %v, want %v", replayed.Inventory, vm.Inventory)
+	}
+	if !reflect.DeepEqual(replayed.CoinStock(), vm.CoinStock()) {
+		t.Errorf("Replay() coins = %v, want %v", replayed.CoinStock(), vm.CoinStock())
+	}
+	if replayed.Balance() != 15 || vm.Balance() != 15 {
+		t.Errorf("Replay() balance = %v, want 15", replayed.Balance())
+	}
+	if replayed.Cancel() != vm.Cancel() {
+		t.Errorf("Replay() pending credit does not match the live machine")
+	}
+}
+
+func TestReplayRejectsInconsistentLog(t *testing.T) {
+	events := []Event{
+		{Type: EventInsertCoin, Amount: 25, Result: ResultOK},
+		{Type: EventSelectProduct, Product: "Cola", Amount: 25, Result: ResultOK},
+		{Type: EventInsertCoin, Amount: 25, Result: ResultOK},
+		{Type: EventSelectProduct, Product: "Cola", Amount: 25, Result: ResultOK},
+	}
+	if _, err := Replay(map[string]Item{"Cola": {Name: "Cola", Price: 25, Stock: 1}}, events); err == nil {
+		t.Errorf("Replay() expected error for dispensing more than stocked but got none")
+	}
+
+	events = []Event{{Type: EventEmptyCoins, Coins: Change{25: 1}, Result: ResultOK}}
+	if _, err := Replay(map[string]Item{}, events); err == nil {
+		t.Errorf("Replay() expected error for emptying missing coins but got none")
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Append(Event) error       { return errors.New("disk full") }
+func (failingStore) Events() ([]Event, error) { return nil, nil }
+
+func TestEventStoreFailure(t *testing.T) {
+	vm := newLoggedMachine(failingStore{})
+
+	if err := vm.InsertCoin(25); err == nil {
+		t.Fatalf("InsertCoin() expected error when the event cannot be recorded")
+	}
+	if vm.Balance() != 0 {
+		t.Errorf("InsertCoin() balance = %v, want 0 when unrecorded", vm.Balance())
+	}
+
+	vm.UseEventStore(nil)
+	vm.InsertCoin(25)
+	vm.UseEventStore(failingStore{})
+	if refund := vm.Cancel(); refund != 25 {
+		t.Errorf("Cancel() refund = %v, want 25 even when unrecorded", refund)
+	}
+}
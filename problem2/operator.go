@@ -0,0 +1,275 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+type Role int
+
+const (
+	RoleTechnician Role = iota + 1
+	RoleManager
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleTechnician:
+		return "technician"
+	case RoleManager:
+		return "manager"
+	case RoleAdmin:
+		return "admin"
+	}
+	return fmt.Sprintf("Role(%d)", int(r))
+}
+
+type Permission int
+
+const (
+	PermRestock Permission = iota
+	PermMaintenance
+	PermSetPrice
+	PermManageCatalogue
+	PermManageOperators
+	PermManageCash
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermRestock:
+		return "restock"
+	case PermMaintenance:
+		return "lock for maintenance"
+	case PermSetPrice:
+		return "change prices"
+	case PermManageCatalogue:
+		return "manage the catalogue"
+	case PermManageOperators:
+		return "manage operators"
+	case PermManageCash:
+		return "manage cash"
+	}
+	return fmt.Sprintf("Permission(%d)", int(p))
+}
+
+var rolePermissions = map[Role][]Permission{
+	RoleTechnician: {PermRestock, PermMaintenance},
+	RoleManager:    {PermRestock, PermMaintenance, PermSetPrice, PermManageCatalogue, PermManageCash},
+	RoleAdmin:      {PermRestock, PermMaintenance, PermSetPrice, PermManageCatalogue, PermManageOperators, PermManageCash},
+}
+
+var ErrUnauthorized = errors.New("invalid operator credentials")
+
+type PermissionError struct {
+	Operator   string
+	Role       Role
+	Permission Permission
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("operator %v (%v) is not allowed to %v", e.Operator, e.Role, e.Permission)
+}
+
+type Credentials struct {
+	ID  string
+	PIN string
+}
+
+type operatorAccount struct {
+	role    Role
+	pinHash [32]byte
+}
+
+// RegisterOperator adds an operator account on behalf of an admin. The first
+// account needs no credentials, but it must be an admin so that the machine
+// can never be left without someone able to register the rest.
+func (v *VendingMachine) RegisterOperator(cred Credentials, id, pin string, role Role) error {
+	if id == "" || pin == "" {
+		return fmt.Errorf("operator id and pin are required")
+	}
+	if _, ok := rolePermissions[role]; !ok {
+		return fmt.Errorf("unknown role %v", role)
+	}
+
+	v.Lock()
+	defer v.Unlock()
+	if len(v.operators) == 0 {
+		if role != RoleAdmin {
+			return fmt.Errorf("the first operator must be an %v, got %v", RoleAdmin, role)
+		}
+	} else if err := v.authorize(cred, PermManageOperators); err != nil {
+		return err
+	}
+	if _, ok := v.operators[id]; ok {
+		return fmt.Errorf("operator %v already registered", id)
+	}
+	v.operators[id] = operatorAccount{role: role, pinHash: sha256.Sum256([]byte(pin))}
+	return nil
+}
+
+func (v *VendingMachine) authorize(cred Credentials, perm Permission) error {
+	account, ok := v.operators[cred.ID]
+	hash := sha256.Sum256([]byte(cred.PIN))
+	if !ok || subtle.ConstantTimeCompare(hash[:], account.pinHash[:]) != 1 {
+		return ErrUnauthorized
+	}
+	for _, p := range rolePermissions[account.role] {
+		if p == perm {
+			return nil
+		}
+	}
+	return &PermissionError{Operator: cred.ID, Role: account.role, Permission: perm}
+}
+
+// Products returns the catalogue sorted by name.
+func (v *VendingMachine) Products() []Item {
+	v.Lock()
+	defer v.Unlock()
+	items := make([]Item, 0, len(v.Inventory))
+	for _, item := range v.Inventory {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items
+}
+
+func (v *VendingMachine) Product(name string) (Item, bool) {
+	v.Lock()
+	defer v.Unlock()
+	item, ok := v.Inventory[name]
+	return item, ok
+}
+
+func (v *VendingMachine) AddProduct(cred Credentials, item Item) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventAddProduct, Operator: cred.ID, Product: item.Name, Amount: item.Price, Quantity: item.Stock}
+	if err := v.authorize(cred, PermManageCatalogue); err != nil {
+		return v.reject(e, err)
+	}
+	if item.Name == "" {
+		return v.reject(e, fmt.Errorf("%w: product name is required", ErrInvalidArgument))
+	}
+	if item.Price < 0 || item.Stock < 0 {
+		return v.reject(e, fmt.Errorf("%w: price and stock must not be negative", ErrInvalidArgument))
+	}
+	if _, ok := v.Inventory[item.Name]; ok {
+		return v.reject(e, fmt.Errorf("%w: %v", ErrProductExists, item.Name))
+	}
+	return v.commit(e)
+}
+
+func (v *VendingMachine) RemoveProduct(cred Credentials, productName string) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventRemoveProduct, Operator: cred.ID, Product: productName}
+	if err := v.authorize(cred, PermManageCatalogue); err != nil {
+		return v.reject(e, err)
+	}
+	if _, ok := v.Inventory[productName]; !ok {
+		return v.reject(e, fmt.Errorf("%w : %v", ErrProductNotFound, productName))
+	}
+	return v.commit(e)
+}
+
+func (v *VendingMachine) Restock(cred Credentials, productName string, quantity int) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventRestock, Operator: cred.ID, Product: productName, Quantity: quantity}
+	if err := v.authorize(cred, PermRestock); err != nil {
+		return v.reject(e, err)
+	}
+	if _, ok := v.Inventory[productName]; !ok {
+		return v.reject(e, fmt.Errorf("%w : %v", ErrProductNotFound, productName))
+	}
+	if quantity <= 0 {
+		return v.reject(e, fmt.Errorf("%w: restock quantity must be positive, got %d", ErrInvalidArgument, quantity))
+	}
+	if len(v.slotsFor(productName)) > 0 {
+		filled, err := v.fillSlots(productName, quantity)
+		if err != nil {
+			return v.reject(e, err)
+		}
+		e.Slots = filled
+	}
+	return v.commit(e)
+}
+
+func (v *VendingMachine) SetPrice(cred Credentials, productName string, price int) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventSetPrice, Operator: cred.ID, Product: productName, Amount: price}
+	if err := v.authorize(cred, PermSetPrice); err != nil {
+		return v.reject(e, err)
+	}
+	if _, ok := v.Inventory[productName]; !ok {
+		return v.reject(e, fmt.Errorf("%w : %v", ErrProductNotFound, productName))
+	}
+	if price < 0 {
+		return v.reject(e, fmt.Errorf("%w: price must not be negative, got %d", ErrInvalidArgument, price))
+	}
+	return v.commit(e)
+}
+
+func (v *VendingMachine) LockForMaintenance(cred Credentials) (int, error) {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventEnterMaintenance, Operator: cred.ID}
+	if err := v.authorize(cred, PermMaintenance); err != nil {
+		return 0, v.reject(e, err)
+	}
+	if state := v.state(); state == StateMaintenance || state == StateDispensing {
+		return 0, v.reject(e, &TransitionError{State: state, Action: ActionEnterMaintenance})
+	}
+
+	ids := make([]string, 0, len(v.sessions))
+	for id := range v.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	refund := 0
+	for _, id := range ids {
+		credit := v.sessions[id].balance
+		if credit == 0 {
+			continue
+		}
+		from := v.state()
+		cancel := Event{Type: EventCancel, Operator: cred.ID, Session: id, Amount: credit}
+		if err := v.commit(cancel); err != nil {
+			return refund, err
+		}
+		refund += credit
+		v.notify(from, ActionCancel)
+	}
+
+	from := v.state()
+	if err := v.commit(e); err != nil {
+		return refund, err
+	}
+	v.notify(from, ActionEnterMaintenance)
+	return refund, nil
+}
+
+func (v *VendingMachine) EndMaintenance(cred Credentials) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventExitMaintenance, Operator: cred.ID}
+	if err := v.authorize(cred, PermMaintenance); err != nil {
+		return v.reject(e, err)
+	}
+	if err := v.checkTransition(ActionExitMaintenance); err != nil {
+		return v.reject(e, err)
+	}
+	from := v.state()
+	if err := v.commit(e); err != nil {
+		return err
+	}
+	v.notify(from, ActionExitMaintenance)
+	return nil
+}
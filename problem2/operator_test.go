@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var (
+	technician = Credentials{ID: "tina", PIN: "1111"}
+	manager    = Credentials{ID: "max", PIN: "2222"}
+	admin      = Credentials{ID: "ada", PIN: "0000"}
+)
+
+// registerOperator registers cred through the admin account, creating the
+// admin first if the machine has no operators yet.
+func registerOperator(t *testing.T, vm *VendingMachine, cred Credentials, role Role) {
+	t.Helper()
+	if len(vm.operators) == 0 {
+		if err := vm.RegisterOperator(Credentials{}, admin.ID, admin.PIN, RoleAdmin); err != nil {
+			t.Fatalf("RegisterOperator() unexpected error = %v", err)
+		}
+	}
+	if err := vm.RegisterOperator(admin, cred.ID, cred.PIN, role); err != nil {
+		t.Fatalf("RegisterOperator() unexpected error = %v", err)
+	}
+}
+
+func newOperatedMachine(t *testing.T) *VendingMachine {
+	t.Helper()
+	vm := NewVendingMachine(map[string]Item{
+		"Cola": {Name: "Cola", Price: 25, Stock: 1},
+	})
+	registerOperator(t, vm, technician, RoleTechnician)
+	registerOperator(t, vm, manager, RoleManager)
+	return vm
+}
+
+func TestRegisterOperator(t *testing.T) {
+	vm := newOperatedMachine(t)
+
+	if err := vm.RegisterOperator(admin, "tina", "9999", RoleAdmin); err == nil {
+		t.Errorf("RegisterOperator() expected error for duplicate id but got none")
+	}
+	if err := vm.RegisterOperator(admin, "", "9999", RoleAdmin); err == nil {
+		t.Errorf("RegisterOperator() expected error for empty id but got none")
+	}
+	if err := vm.RegisterOperator(admin, "eve", "9999", Role(42)); err == nil {
+		t.Errorf("RegisterOperator() expected error for unknown role but got none")
+	}
+	if err := vm.RegisterOperator(Credentials{}, "eve", "9999", RoleAdmin); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("RegisterOperator() without credentials error = %v, want ErrUnauthorized", err)
+	}
+	var permissionErr *PermissionError
+	if err := vm.RegisterOperator(manager, "eve", "9999", RoleAdmin); !errors.As(err, &permissionErr) || permissionErr.Permission != PermManageOperators {
+		t.Errorf("RegisterOperator() by a manager error = %v, want *PermissionError", err)
+	}
+	if err := vm.RegisterOperator(admin, "eve", "9999", RoleManager); err != nil {
+		t.Errorf("RegisterOperator() by an admin unexpected error = %v", err)
+	}
+}
+
+func TestRegisterFirstOperator(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{})
+
+	if err := vm.RegisterOperator(Credentials{}, "tina", "1111", RoleTechnician); err == nil {
+		t.Errorf("RegisterOperator() expected error for a first operator that is not an admin")
+	}
+	if err := vm.RegisterOperator(Credentials{}, admin.ID, admin.PIN, RoleAdmin); err != nil {
+		t.Fatalf("RegisterOperator() first admin unexpected error = %v", err)
+	}
+	if err := vm.RegisterOperator(Credentials{}, "mallory", "6666", RoleAdmin); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("RegisterOperator() second operator without credentials error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestOperatorPermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		cred    Credentials
+		op      func(vm *VendingMachine, cred Credentials) error
+		wantErr error
+		perm    Permission
+	}{
+		{
+			name: "technician restocks",
+			cred: technician,
+			op:   func(vm *VendingMachine, c Credentials) error { return vm.Restock(c, "Cola", 5) },
+		},
+		{
+			name:    "technician cannot reprice",
+			cred:    technician,
+			op:      func(vm *VendingMachine, c Credentials) error { return vm.SetPrice(c, "Cola", 30) },
+			wantErr: &PermissionError{},
+			perm:    PermSetPrice,
+		},
+		{
+			name:    "technician cannot add products",
+			cred:    technician,
+			op:      func(vm *VendingMachine, c Credentials) error { return vm.AddProduct(c, Item{Name: "Gum", Price: 5}) },
+			wantErr: &PermissionError{},
+			perm:    PermManageCatalogue,
+		},
+		{
+			name:    "technician cannot load coins",
+			cred:    technician,
+			op:      func(vm *VendingMachine, c Credentials) error { return vm.LoadCoins(c, map[int]int{25: 1}) },
+			wantErr: &PermissionError{},
+			perm:    PermManageCash,
+		},
+		{
+			name: "manager empties coins",
+			cred: manager,
+			op: func(vm *VendingMachine, c Credentials) error {
+				_, err := vm.EmptyCoins(c)
+				return err
+			},
+		},
+		{
+			name: "manager reprices",
+			cred: manager,
+			op:   func(vm *VendingMachine, c Credentials) error { return vm.SetPrice(c, "Cola", 30) },
+		},
+		{
+			name:    "wrong pin",
+			cred:    Credentials{ID: "max", PIN: "0000"},
+			op:      func(vm *VendingMachine, c Credentials) error { return vm.Restock(c, "Cola", 5) },
+			wantErr: ErrUnauthorized,
+		},
+		{
+			name:    "unknown operator",
+			cred:    Credentials{ID: "eve", PIN: "2222"},
+			op:      func(vm *VendingMachine, c Credentials) error { return vm.RemoveProduct(c, "Cola") },
+			wantErr: ErrUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newOperatedMachine(t)
+			before := vm.Inventory["Cola"]
+			err := tt.op(vm, tt.cred)
+
+			switch want := tt.wantErr.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("operation unexpected error = %v", err)
+				}
+			case *PermissionError:
+				var permErr *PermissionError
+				if !errors.As(err, &permErr) {
+					t.Fatalf("operation error = %v, want *PermissionError", err)
+				}
+				if permErr.Permission != tt.perm || permErr.Operator != tt.cred.ID {
+					t.Errorf("operation error = %+v, want %v denied to %v", permErr, tt.perm, tt.cred.ID)
+				}
+			default:
+				if !errors.Is(err, want) {
+					t.Errorf("operation error = %v, want %v", err, want)
+				}
+			}
+			if tt.wantErr != nil && vm.Inventory["Cola"] != before {
+				t.Errorf("operation changed the inventory despite failing: %v", vm.Inventory["Cola"])
+			}
+		})
+	}
+}
+
+func TestCatalogueManagement(t *testing.T) {
+	vm := newOperatedMachine(t)
+
+	if err := vm.AddProduct(manager, Item{Name: "Gum", Price: 5, Stock: 3}); err != nil {
+		t.Fatalf("AddProduct() unexpected error = %v", err)
+	}
+	if err := vm.AddProduct(manager, Item{Name: "Gum", Price: 5}); err == nil {
+		t.Errorf("AddProduct() expected error for duplicate product but got none")
+	}
+	if err := vm.AddProduct(manager, Item{Name: "Mints", Price: -1}); err == nil {
+		t.Errorf("AddProduct() expected error for negative price but got none")
+	}
+	if err := vm.Restock(technician, "Gum", 2); err != nil {
+		t.Fatalf("Restock() unexpected error = %v", err)
+	}
+	if err := vm.Restock(technician, "Gum", 0); err == nil {
+		t.Errorf("Restock() expected error for zero quantity but got none")
+	}
+	if err := vm.Restock(technician, "Nope", 2); err == nil {
+		t.Errorf("Restock() expected error for unknown product but got none")
+	}
+	if err := vm.SetPrice(manager, "Gum", 10); err != nil {
+		t.Fatalf("SetPrice() unexpected error = %v", err)
+	}
+	if want := (Item{Name: "Gum", Price: 10, Stock: 5}); vm.Inventory["Gum"] != want {
+		t.Errorf("Inventory[Gum] = %v, want %v", vm.Inventory["Gum"], want)
+	}
+	want := []Item{{Name: "Cola", Price: 25, Stock: 1}, {Name: "Gum", Price: 10, Stock: 5}}
+	if got := vm.Products(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Products() = %v, want %v", got, want)
+	}
+
+	if err := vm.RemoveProduct(manager, "Cola"); err != nil {
+		t.Fatalf("RemoveProduct() unexpected error = %v", err)
+	}
+	if item, ok := vm.Product("Cola"); ok {
+		t.Errorf("Product(Cola) = %v, want it removed", item)
+	}
+	vm.InsertCoin(25)
+	if _, _, err := vm.SelectProduct("Cola"); err == nil {
+		t.Errorf("SelectProduct() expected error for removed product but got none")
+	}
+}
+
+func TestLockForMaintenanceRefundsCredit(t *testing.T) {
+	vm := newOperatedMachine(t)
+	var changes []StateChange
+	vm.Subscribe(func(c StateChange) {
+		changes = append(changes, c)
+	})
+
+	vm.InsertCoin(10)
+	refund, err := vm.LockForMaintenance(technician)
+	if err != nil {
+		t.Fatalf("LockForMaintenance() unexpected error = %v", err)
+	}
+	if refund != 10 {
+		t.Errorf("LockForMaintenance() refund = %v, want 10", refund)
+	}
+	if vm.State() != StateMaintenance {
+		t.Errorf("State() = %v, want %v", vm.State(), StateMaintenance)
+	}
+	if _, err := vm.LockForMaintenance(technician); err == nil {
+		t.Errorf("LockForMaintenance() expected error when already locked but got none")
+	}
+
+	if err := vm.Restock(technician, "Cola", 4); err != nil {
+		t.Errorf("Restock() during maintenance unexpected error = %v", err)
+	}
+	if err := vm.EndMaintenance(Credentials{ID: "tina", PIN: "nope"}); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("EndMaintenance() error = %v, want %v", err, ErrUnauthorized)
+	}
+	if err := vm.EndMaintenance(technician); err != nil {
+		t.Fatalf("EndMaintenance() unexpected error = %v", err)
+	}
+
+	want := []StateChange{
+		{From: StateIdle, To: StateHasCredit, Action: ActionInsertCoin},
+		{From: StateHasCredit, To: StateIdle, Action: ActionCancel},
+		{From: StateIdle, To: StateMaintenance, Action: ActionEnterMaintenance},
+		{From: StateMaintenance, To: StateIdle, Action: ActionExitMaintenance},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Subscribe() changes = %v, want %v", changes, want)
+	}
+}
+
+func TestOperatorEventsReplay(t *testing.T) {
+	store := &MemoryEventStore{}
+	vm := newOperatedMachine(t)
+	vm.UseEventStore(store)
+
+	vm.AddProduct(manager, Item{Name: "Gum", Price: 5, Stock: 3})
+	vm.Restock(technician, "Cola", 4)
+	vm.SetPrice(manager, "Cola", 30)
+	vm.SetPrice(technician, "Gum", 1)
+	vm.RemoveProduct(manager, "Gum")
+
+	events, _ := store.Events()
+	if events[3].Result == ResultOK || events[3].Operator != "tina" {
+		t.Errorf("Events()[3] = %+v, want the denied reprice by tina", events[3])
+	}
+	replayed, err := Replay(map[string]Item{"Cola": {Name: "Cola", Price: 25, Stock: 1}}, events)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(replayed.Inventory, vm.Inventory) {
+		t.Errorf("Replay() inventory = %v, want %v", replayed.Inventory, vm.Inventory)
+	}
+}
+
+func TestRestockConcurrentWithPurchases(t *testing.T) {
+	vm := newOperatedMachine(t)
+
+	sold := 0
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			vm.InsertCoin(25)
+			if _, _, err := vm.SelectProduct("Cola"); err == nil {
+				sold++
+			}
+		}
+		vm.Cancel()
+	}()
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vm.Restock(technician, "Cola", 1)
+			vm.SetPrice(manager, "Cola", 25)
+		}()
+	}
+	wg.Wait()
+
+	if got := vm.Inventory["Cola"].Stock; got != 21-sold {
+		t.Errorf("Inventory[Cola].Stock = %v, want %v after selling %d", got, 21-sold, sold)
+	}
+}
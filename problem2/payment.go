@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrPaymentDeclined      = errors.New("payment declined")
+	ErrUnknownAuthorization = errors.New("unknown authorization")
+	ErrPriceChanged         = errors.New("price changed during payment")
+)
+
+// A PaymentMethod pays for a sale without cash. The machine authorizes the
+// sale total, dispenses, and then captures the payment; if the sale fails
+// after authorizing, the authorization is voided instead.
+type PaymentMethod interface {
+	Name() string
+	Authorize(amount int) (Authorization, error)
+	Capture(auth Authorization) error
+	Void(auth Authorization) error
+}
+
+type Authorization struct {
+	ID     string
+	Amount int
+}
+
+type AuthStatus int
+
+const (
+	AuthAuthorized AuthStatus = iota
+	AuthCaptured
+	AuthVoided
+)
+
+func (s AuthStatus) String() string {
+	switch s {
+	case AuthAuthorized:
+		return "authorized"
+	case AuthCaptured:
+		return "captured"
+	case AuthVoided:
+		return "voided"
+	}
+	return fmt.Sprintf("AuthStatus(%d)", int(s))
+}
+
+// authLedger keeps the state of every authorization a payment method issued.
+type authLedger struct {
+	mu     sync.Mutex
+	prefix string
+	next   int
+	auths  map[string]*ledgerEntry
+}
+
+type ledgerEntry struct {
+	amount int
+	status AuthStatus
+}
+
+func (l *authLedger) open(amount int) Authorization {
+	if l.auths == nil {
+		l.auths = make(map[string]*ledgerEntry)
+	}
+	l.next++
+	auth := Authorization{ID: fmt.Sprintf("%v-%d", l.prefix, l.next), Amount: amount}
+	l.auths[auth.ID] = &ledgerEntry{amount: amount, status: AuthAuthorized}
+	return auth
+}
+
+func (l *authLedger) settle(auth Authorization, status AuthStatus) (*ledgerEntry, error) {
+	entry, ok := l.auths[auth.ID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownAuthorization, auth.ID)
+	}
+	if entry.status != AuthAuthorized {
+		return nil, fmt.Errorf("authorization %v is already %v", auth.ID, entry.status)
+	}
+	entry.status = status
+	return entry, nil
+}
+
+func (l *authLedger) held() int {
+	held := 0
+	for _, entry := range l.auths {
+		if entry.status == AuthAuthorized {
+			held += entry.amount
+		}
+	}
+	return held
+}
+
+func (l *authLedger) Status(id string) (AuthStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.auths[id]
+	if !ok {
+		return 0, false
+	}
+	return entry.status, true
+}
+
+// PrepaidCard holds authorized amounts against its balance until they are
+// captured or voided.
+type PrepaidCard struct {
+	authLedger
+	ID      string
+	balance int
+}
+
+func NewPrepaidCard(id string, balance int) *PrepaidCard {
+	return &PrepaidCard{authLedger: authLedger{prefix: id}, ID: id, balance: balance}
+}
+
+func (c *PrepaidCard) Name() string {
+	return "prepaid:" + c.ID
+}
+
+func (c *PrepaidCard) Balance() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.balance - c.held()
+}
+
+func (c *PrepaidCard) TopUp(amount int) error {
+	if amount <= 0 {
+		return fmt.Errorf("%w: top-up must be positive, got %d", ErrInvalidArgument, amount)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.balance += amount
+	return nil
+}
+
+func (c *PrepaidCard) Authorize(amount int) (Authorization, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if available := c.balance - c.held(); available < amount {
+		return Authorization{}, fmt.Errorf("%w: card %v has %d available, need %d", ErrPaymentDeclined, c.ID, available, amount)
+	}
+	return c.open(amount), nil
+}
+
+func (c *PrepaidCard) Capture(auth Authorization) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, err := c.settle(auth, AuthCaptured)
+	if err != nil {
+		return err
+	}
+	c.balance -= entry.amount
+	return nil
+}
+
+func (c *PrepaidCard) Void(auth Authorization) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.settle(auth, AuthVoided)
+	return err
+}
+
+// MockCardTerminal approves every authorization up to Limit. Setting Decline
+// or FailCapture simulates a declined card or a capture that the acquirer
+// rejects.
+type MockCardTerminal struct {
+	authLedger
+	Limit       int
+	Decline     bool
+	FailCapture bool
+}
+
+func NewMockCardTerminal(limit int) *MockCardTerminal {
+	return &MockCardTerminal{authLedger: authLedger{prefix: "card"}, Limit: limit}
+}
+
+func (t *MockCardTerminal) Name() string {
+	return "card"
+}
+
+func (t *MockCardTerminal) Authorize(amount int) (Authorization, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Decline || amount > t.Limit {
+		return Authorization{}, fmt.Errorf("%w by card terminal", ErrPaymentDeclined)
+	}
+	return t.open(amount), nil
+}
+
+func (t *MockCardTerminal) Capture(auth Authorization) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.FailCapture {
+		return fmt.Errorf("card terminal failed to capture %v", auth.ID)
+	}
+	_, err := t.settle(auth, AuthCaptured)
+	return err
+}
+
+func (t *MockCardTerminal) Void(auth Authorization) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.settle(auth, AuthVoided)
+	return err
+}
+
+func (v *VendingMachine) BuyWith(method PaymentMethod, items map[string]int) (*Receipt, error) {
+	return v.buy("", items, method, "")
+}
+
+func (s *Session) BuyWith(method PaymentMethod, items map[string]int) (*Receipt, error) {
+	return s.vm.buy(s.ID, items, method, "")
+}
+
+// payAndDispense talks to the payment provider without holding the machine's
+// lock, so a slow terminal never stalls other sessions. The sale is checked
+// and priced again once the payment is authorized, and the authorization is
+// voided if the sale does not go through. The product is already out of the
+// machine when the payment is captured, so a failed capture voids the
+// authorization and returns the receipt together with the error.
+func (v *VendingMachine) payAndDispense(id string, items map[string]int, method PaymentMethod, slotCode string) (*Receipt, error) {
+	v.Lock()
+	e, quoted, _, err := v.quote(id, items, slotCode)
+	v.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	auth, err := method.Authorize(quoted.Total)
+	if err != nil {
+		v.Lock()
+		defer v.Unlock()
+		return nil, v.reject(e, err)
+	}
+
+	receipt, err := v.commitPayment(id, items, slotCode, method, auth)
+	if err != nil {
+		return nil, voidPayment(method, auth, err)
+	}
+	if err := method.Capture(auth); err != nil {
+		v.Lock()
+		v.recordPaymentFailure(id, method, receipt)
+		v.Unlock()
+		return receipt, voidPayment(method, auth, fmt.Errorf("failed to capture payment: %w", err))
+	}
+	return receipt, nil
+}
+
+// recordPaymentFailure records that a dispensed sale was never paid for, so
+// that the sales history and a replay count no revenue for it.
+func (v *VendingMachine) recordPaymentFailure(id string, method PaymentMethod, receipt *Receipt) {
+	e := Event{Type: EventPaymentFailed, Session: id, Payment: method.Name(), Amount: receipt.Total, Items: map[string]int{}}
+	for _, line := range receipt.Items {
+		e.Items[line.Product] += line.Quantity
+	}
+	v.settle(&e)
+}
+
+func (v *VendingMachine) commitPayment(id string, items map[string]int, slotCode string, method PaymentMethod, auth Authorization) (*Receipt, error) {
+	v.Lock()
+	defer v.Unlock()
+	e, receipt, _, err := v.quote(id, items, slotCode)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Total != auth.Amount {
+		return nil, v.reject(e, fmt.Errorf("%w: total went from %d to %d", ErrPriceChanged, auth.Amount, receipt.Total))
+	}
+
+	from := v.state()
+	e.Amount = receipt.Total
+	e.Payment = method.Name()
+	if err := v.commit(e); err != nil {
+		return nil, err
+	}
+	v.dispense(from, receipt.Items)
+	return receipt, nil
+}
+
+func voidPayment(method PaymentMethod, auth Authorization, err error) error {
+	if voidErr := method.Void(auth); voidErr != nil {
+		return errors.Join(err, fmt.Errorf("failed to void payment: %w", voidErr))
+	}
+	return err
+}
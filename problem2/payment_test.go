@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfiguredDenominations(t *testing.T) {
+	vm := NewVendingMachineWithConfig(map[string]Item{"Cola": {Name: "Cola", Price: 150, Stock: 2}},
+		Config{Coins: []int{50, 100}, Banknotes: []int{200}})
+
+	if err := vm.InsertCoin(25); !errors.Is(err, ErrInvalidCoin) {
+		t.Errorf("InsertCoin(25) error = %v, want %v", err, ErrInvalidCoin)
+	}
+	if err := vm.InsertBanknote(500); !errors.Is(err, ErrInvalidBanknote) {
+		t.Errorf("InsertBanknote(500) error = %v, want %v", err, ErrInvalidBanknote)
+	}
+	loadCoins(t, vm, map[int]int{50: 2})
+	if err := vm.LoadCoins(admin, map[int]int{5: 1}); !errors.Is(err, ErrInvalidCoin) {
+		t.Errorf("LoadCoins() error = %v, want %v", err, ErrInvalidCoin)
+	}
+	if err := vm.InsertBanknote(200); err != nil {
+		t.Fatalf("InsertBanknote(200) unexpected error = %v", err)
+	}
+	change, _, err := vm.SelectProduct("Cola")
+	if err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(change, Change{50: 1}) {
+		t.Errorf("SelectProduct() change = %v, want one 50 coin", change)
+	}
+	if !reflect.DeepEqual(vm.Banknotes, map[int]int{200: 1}) {
+		t.Errorf("Banknotes = %v, want the 200 note kept in the machine", vm.Banknotes)
+	}
+}
+
+func TestBanknotesAreRefunded(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{})
+	sess, _ := vm.StartSession()
+	sess.InsertBanknote(100)
+	sess.InsertCoin(25)
+
+	if refund := sess.Cancel(); refund != 125 {
+		t.Errorf("Cancel() refund = %v, want 125", refund)
+	}
+	if len(vm.Banknotes) != 0 {
+		t.Errorf("Banknotes = %v, want none kept after a refund", vm.Banknotes)
+	}
+}
+
+type authStatuses interface {
+	Status(id string) (AuthStatus, bool)
+}
+
+func TestBuyWithPaymentMethod(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    func() PaymentMethod
+		authID    string
+		wantErr   error
+		wantStock int
+	}{
+		{
+			name:      "prepaid card",
+			method:    func() PaymentMethod { return NewPrepaidCard("c1", 100) },
+			authID:    "c1-1",
+			wantStock: 9,
+		},
+		{
+			name:      "prepaid card without funds",
+			method:    func() PaymentMethod { return NewPrepaidCard("c1", 40) },
+			wantErr:   ErrPaymentDeclined,
+			wantStock: 10,
+		},
+		{
+			name:      "card terminal",
+			method:    func() PaymentMethod { return NewMockCardTerminal(1000) },
+			authID:    "card-1",
+			wantStock: 9,
+		},
+		{
+			name: "card declined",
+			method: func() PaymentMethod {
+				terminal := NewMockCardTerminal(1000)
+				terminal.Decline = true
+				return terminal
+			},
+			wantErr:   ErrPaymentDeclined,
+			wantStock: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newPricedMachine(t, time.Now(), ComboRule{Products: []string{"Chips", "Cola"}, Price: 50})
+			vm.InsertCoin(25)
+			method := tt.method()
+
+			receipt, err := vm.BuyWith(method, map[string]int{"Chips": 1, "Cola": 1})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("BuyWith() error = %v, want %v", err, tt.wantErr)
+			}
+			if vm.Inventory["Cola"].Stock != tt.wantStock {
+				t.Errorf("Inventory[Cola].Stock = %v, want %v", vm.Inventory["Cola"].Stock, tt.wantStock)
+			}
+			if vm.Balance() != 25 {
+				t.Errorf("Balance() = %v, want cash credit untouched by a %v sale", vm.Balance(), method.Name())
+			}
+			if err != nil {
+				return
+			}
+			if receipt.Total != 50 || len(receipt.Change) != 0 {
+				t.Errorf("BuyWith() receipt = %v, want total 50 without change", receipt)
+			}
+			if status, _ := method.(authStatuses).Status(tt.authID); status != AuthCaptured {
+				t.Errorf("Status(%v) = %v, want %v", tt.authID, status, AuthCaptured)
+			}
+		})
+	}
+}
+
+func TestBuyWithVoidsFailedSale(t *testing.T) {
+	vm := newLoggedMachine(failingStore{})
+	card := NewPrepaidCard("c1", 100)
+
+	if _, err := vm.BuyWith(card, map[string]int{"Cola": 1}); err == nil {
+		t.Fatalf("BuyWith() expected error when the sale cannot be recorded")
+	}
+	if status, _ := card.Status("c1-1"); status != AuthVoided {
+		t.Errorf("Status() = %v, want %v", status, AuthVoided)
+	}
+	if card.Balance() != 100 || vm.Inventory["Cola"].Stock != 5 {
+		t.Errorf("BuyWith() card balance = %v, stock = %v, want 100, 5", card.Balance(), vm.Inventory["Cola"].Stock)
+	}
+}
+
+func TestBuyWithCaptureFailure(t *testing.T) {
+	store := &MemoryEventStore{}
+	vm := newLoggedMachine(store)
+	terminal := NewMockCardTerminal(1000)
+	terminal.FailCapture = true
+
+	receipt, err := vm.BuyWith(terminal, map[string]int{"Cola": 1})
+	if err == nil || receipt == nil {
+		t.Fatalf("BuyWith() = %v, %v, want the receipt and a capture error", receipt, err)
+	}
+	if vm.Inventory["Cola"].Stock != 4 {
+		t.Errorf("Inventory[Cola].Stock = %v, want 4 as the product was dispensed", vm.Inventory["Cola"].Stock)
+	}
+	if status, _ := terminal.Status("card-1"); status != AuthVoided {
+		t.Errorf("Status() = %v, want %v after the failed capture", status, AuthVoided)
+	}
+	want := []ProductSales{{Product: "Cola", Units: 1, Revenue: 0}}
+	if got := vm.SalesReport(time.Time{}, time.Time{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("SalesReport() = %v, want %v", got, want)
+	}
+
+	events, _ := store.Events()
+	replayed, err := Replay(map[string]Item{
+		"Cola":  {Name: "Cola", Price: 25, Stock: 5},
+		"Chips": {Name: "Chips", Price: 35, Stock: 10},
+	}, events)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(replayed.Sales(time.Time{}, time.Time{}), vm.Sales(time.Time{}, time.Time{})) {
+		t.Errorf("Replay() sales = %v, want %v", replayed.Sales(time.Time{}, time.Time{}), vm.Sales(time.Time{}, time.Time{}))
+	}
+	if got := replayed.SalesReport(time.Time{}, time.Time{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Replay() SalesReport() = %v, want %v", got, want)
+	}
+}
+
+// slowTerminal runs a callback inside each call to the terminal, standing in
+// for a provider that takes a while to answer.
+type slowTerminal struct {
+	*MockCardTerminal
+	onAuthorize func()
+	onCapture   func()
+}
+
+func (t *slowTerminal) Authorize(amount int) (Authorization, error) {
+	t.onAuthorize()
+	return t.MockCardTerminal.Authorize(amount)
+}
+
+func (t *slowTerminal) Capture(auth Authorization) error {
+	t.onCapture()
+	return t.MockCardTerminal.Capture(auth)
+}
+
+func TestBuyWithCallsProviderUnlocked(t *testing.T) {
+	vm := newLoggedMachine(&MemoryEventStore{})
+	var lockedDuring []string
+	checkUnlocked := func(call string) func() {
+		return func() {
+			if !vm.TryLock() {
+				lockedDuring = append(lockedDuring, call)
+				return
+			}
+			vm.Unlock()
+		}
+	}
+	terminal := &slowTerminal{MockCardTerminal: NewMockCardTerminal(1000)}
+	terminal.onAuthorize = checkUnlocked("Authorize")
+	terminal.onCapture = checkUnlocked("Capture")
+
+	if _, err := vm.BuyWith(terminal, map[string]int{"Cola": 1}); err != nil {
+		t.Fatalf("BuyWith() unexpected error = %v", err)
+	}
+	if len(lockedDuring) != 0 {
+		t.Errorf("BuyWith() held the machine locked during %v", lockedDuring)
+	}
+}
+
+func TestBuyWithPriceChangedDuringAuthorization(t *testing.T) {
+	vm := newLoggedMachine(&MemoryEventStore{})
+	registerOperator(t, vm, manager, RoleManager)
+	terminal := &slowTerminal{MockCardTerminal: NewMockCardTerminal(1000), onCapture: func() {}}
+	terminal.onAuthorize = func() {
+		vm.SetPrice(manager, "Cola", 30)
+	}
+
+	if _, err := vm.BuyWith(terminal, map[string]int{"Cola": 1}); !errors.Is(err, ErrPriceChanged) {
+		t.Fatalf("BuyWith() error = %v, want %v", err, ErrPriceChanged)
+	}
+	if status, _ := terminal.Status("card-1"); status != AuthVoided {
+		t.Errorf("Status() = %v, want %v", status, AuthVoided)
+	}
+	if vm.Inventory["Cola"].Stock != 5 {
+		t.Errorf("Inventory[Cola].Stock = %v, want 5", vm.Inventory["Cola"].Stock)
+	}
+}
+
+func TestPrepaidCard(t *testing.T) {
+	card := NewPrepaidCard("c1", 100)
+
+	first, err := card.Authorize(60)
+	if err != nil {
+		t.Fatalf("Authorize() unexpected error = %v", err)
+	}
+	if _, err := card.Authorize(60); !errors.Is(err, ErrPaymentDeclined) {
+		t.Errorf("Authorize() error = %v, want %v while funds are held", err, ErrPaymentDeclined)
+	}
+	if card.Balance() != 40 {
+		t.Errorf("Balance() = %v, want 40 with 60 held", card.Balance())
+	}
+
+	if err := card.Capture(first); err != nil {
+		t.Fatalf("Capture() unexpected error = %v", err)
+	}
+	if err := card.Void(first); err == nil {
+		t.Errorf("Void() expected error for a captured authorization")
+	}
+	if err := card.Capture(Authorization{ID: "c1-9"}); !errors.Is(err, ErrUnknownAuthorization) {
+		t.Errorf("Capture() error = %v, want %v", err, ErrUnknownAuthorization)
+	}
+
+	second, _ := card.Authorize(40)
+	card.Void(second)
+	if card.Balance() != 40 {
+		t.Errorf("Balance() = %v, want 40 after the void", card.Balance())
+	}
+	if err := card.TopUp(0); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("TopUp(0) error = %v, want %v", err, ErrInvalidArgument)
+	}
+}
+
+func TestReplayCardSale(t *testing.T) {
+	store := &MemoryEventStore{}
+	vm := newLoggedMachine(store)
+	vm.InsertCoin(10)
+	vm.BuyWith(NewMockCardTerminal(100), map[string]int{"Cola": 2})
+
+	events, _ := store.Events()
+	replayed, err := Replay(map[string]Item{
+		"Cola":  {Name: "Cola", Price: 25, Stock: 5},
+		"Chips": {Name: "Chips", Price: 35, Stock: 10},
+	}, events)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error = %v", err)
+	}
+	if replayed.Inventory["Cola"].Stock != 3 || replayed.Balance() != 10 {
+		t.Errorf("Replay() stock = %v, balance = %v, want 3, 10", replayed.Inventory["Cola"].Stock, replayed.Balance())
+	}
+}
+
+func TestServerBanknotes(t *testing.T) {
+	srv, _ := newTestServer(t)
+	base := startSession(t, srv)
+
+	status, body := doJSON(t, srv, http.MethodPost, base+"/banknotes", `{"banknote": 100}`, nil)
+	if status != http.StatusOK || body["balance"] != float64(100) {
+		t.Errorf("POST /banknotes = %d %v, want 200 with balance 100", status, body)
+	}
+	if status, _ := doJSON(t, srv, http.MethodPost, base+"/banknotes", `{"banknote": 7}`, nil); status != http.StatusBadRequest {
+		t.Errorf("POST /banknotes with invalid note = %d, want 400", status)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A PricingRule inspects the units of a basket that no earlier rule has
+// claimed and returns the discounts it grants. Units a rule discounts should
+// be claimed so later rules cannot discount them again.
+type PricingRule interface {
+	Apply(b *Basket, at time.Time) []Discount
+}
+
+type Discount struct {
+	Rule   string `json:"rule"`
+	Amount int    `json:"amount"`
+}
+
+type LineItem struct {
+	Product   string `json:"product"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unitPrice"`
+}
+
+type Receipt struct {
+	Items     []LineItem `json:"items"`
+	Discounts []Discount `json:"discounts,omitempty"`
+	Subtotal  int        `json:"subtotal"`
+	Total     int        `json:"total"`
+	Change    Change     `json:"change"`
+}
+
+func (r *Receipt) String() string {
+	var b strings.Builder
+	for _, item := range r.Items {
+		fmt.Fprintf(&b, "%dx %v @ %d\n", item.Quantity, item.Product, item.UnitPrice)
+	}
+	for _, d := range r.Discounts {
+		fmt.Fprintf(&b, "%v: -%d\n", d.Rule, d.Amount)
+	}
+	fmt.Fprintf(&b, "Total: %d, change: %v", r.Total, r.Change)
+	return b.String()
+}
+
+type Basket struct {
+	prices    map[string]int
+	remaining map[string]int
+}
+
+func newBasket(items []LineItem) *Basket {
+	b := &Basket{prices: make(map[string]int), remaining: make(map[string]int)}
+	for _, item := range items {
+		b.prices[item.Product] = item.UnitPrice
+		b.remaining[item.Product] = item.Quantity
+	}
+	return b
+}
+
+func (b *Basket) Count(product string) int {
+	return b.remaining[product]
+}
+
+func (b *Basket) Price(product string) int {
+	return b.prices[product]
+}
+
+func (b *Basket) Claim(product string, n int) {
+	b.remaining[product] -= min(n, b.remaining[product])
+}
+
+type ComboRule struct {
+	Products []string
+	Price    int
+}
+
+func (r ComboRule) Apply(b *Basket, at time.Time) []Discount {
+	if len(r.Products) == 0 {
+		return nil
+	}
+	regular := 0
+	needed := make(map[string]int)
+	for _, p := range r.Products {
+		regular += b.Price(p)
+		needed[p]++
+	}
+	if regular <= r.Price {
+		return nil
+	}
+
+	var discounts []Discount
+	for {
+		for p, n := range needed {
+			if b.Count(p) < n {
+				return discounts
+			}
+		}
+		for p, n := range needed {
+			b.Claim(p, n)
+		}
+		discounts = append(discounts, Discount{
+			Rule:   fmt.Sprintf("%v for %d", strings.Join(r.Products, " + "), r.Price),
+			Amount: regular - r.Price,
+		})
+	}
+}
+
+// PercentOffRule discounts every unit of a product. Setting From or Until
+// limits the discount to the times of day in [From, Until), so From 14h and
+// Until 16h is a happy hour from 14:00 to 16:00. A window whose Until is not
+// after From runs past midnight, so From 22h and Until 2h covers the night.
+type PercentOffRule struct {
+	Product string
+	Percent int
+	From    time.Duration
+	Until   time.Duration
+}
+
+func (r PercentOffRule) Apply(b *Basket, at time.Time) []Discount {
+	count := b.Count(r.Product)
+	if count == 0 || r.Percent <= 0 {
+		return nil
+	}
+	name := fmt.Sprintf("%d%% off %v", r.Percent, r.Product)
+	if r.From != 0 || r.Until != 0 {
+		midnight := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+		offset := at.Sub(midnight)
+		inWindow := offset >= r.From && offset < r.Until
+		if r.Until <= r.From {
+			inWindow = offset >= r.From || offset < r.Until
+		}
+		if !inWindow {
+			return nil
+		}
+		name += fmt.Sprintf(" between %v and %v", clockTime(r.From), clockTime(r.Until))
+	}
+
+	amount := b.Price(r.Product) * count * min(r.Percent, 100) / 100
+	b.Claim(r.Product, count)
+	if amount == 0 {
+		return nil
+	}
+	return []Discount{{Rule: name, Amount: amount}}
+}
+
+type BuyXGetYRule struct {
+	Product string
+	Buy     int
+	Free    int
+}
+
+func (r BuyXGetYRule) Apply(b *Basket, at time.Time) []Discount {
+	if r.Buy <= 0 || r.Free <= 0 {
+		return nil
+	}
+	groups := b.Count(r.Product) / (r.Buy + r.Free)
+	if groups == 0 {
+		return nil
+	}
+	b.Claim(r.Product, groups*(r.Buy+r.Free))
+	return []Discount{{
+		Rule:   fmt.Sprintf("buy %d %v get %d free", r.Buy, r.Product, r.Free),
+		Amount: groups * r.Free * b.Price(r.Product),
+	}}
+}
+
+func (v *VendingMachine) SetPricingRules(cred Credentials, rules ...PricingRule) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventSetPricingRules, Operator: cred.ID, Quantity: len(rules)}
+	if err := v.authorize(cred, PermSetPrice); err != nil {
+		return v.reject(e, err)
+	}
+	if err := v.commit(e); err != nil {
+		return err
+	}
+	v.rules = append([]PricingRule(nil), rules...)
+	return nil
+}
+
+func (v *VendingMachine) Buy(items map[string]int) (*Receipt, error) {
+	return v.buy("", items, nil, "")
+}
+
+func (s *Session) Buy(items map[string]int) (*Receipt, error) {
+	return s.vm.buy(s.ID, items, nil, "")
+}
+
+// Without a payment method the sale is paid from the session's cash. Naming a
+// slot sells one unit from that slot instead of the items.
+func (v *VendingMachine) buy(id string, items map[string]int, method PaymentMethod, slotCode string) (*Receipt, error) {
+	if method != nil {
+		return v.payAndDispense(id, items, method, slotCode)
+	}
+
+	v.Lock()
+	defer v.Unlock()
+	e, receipt, sess, err := v.quote(id, items, slotCode)
+	if err != nil {
+		return nil, err
+	}
+	if sess.balance < receipt.Total {
+		return nil, v.reject(e, fmt.Errorf("%w : %d", ErrInsufficientBalance, sess.balance))
+	}
+
+	available := make(map[int]int, len(v.Coins))
+	for coin, count := range v.Coins {
+		available[coin] = count
+	}
+	for coin, count := range sess.inserted {
+		available[coin] += count
+	}
+	due := sess.balance - receipt.Total
+	change, ok := makeChange(due, available)
+	if !ok {
+		return nil, v.reject(e, fmt.Errorf("%w of %d", ErrNoExactChange, due))
+	}
+
+	from := v.state()
+	e.Amount = receipt.Total
+	e.Coins = change
+	if err := v.commit(e); err != nil {
+		return nil, err
+	}
+	v.dispense(from, receipt.Items)
+	receipt.Change = change
+	return receipt, nil
+}
+
+// quote checks that a sale can go ahead and prices it, logging the sale as
+// rejected if it cannot. The machine must be locked.
+func (v *VendingMachine) quote(id string, items map[string]int, slotCode string) (Event, *Receipt, *session, error) {
+	v.expireSessions()
+	e := Event{Type: EventSelectProduct, Session: id}
+	for name, quantity := range items {
+		if len(items) == 1 && quantity == 1 {
+			e.Product = name
+		}
+	}
+	if e.Product == "" && len(items) > 0 {
+		e.Items = maps.Clone(items)
+	}
+
+	sess, err := v.session(id)
+	if err != nil {
+		return e, nil, nil, v.reject(e, err)
+	}
+	if err := v.checkTransition(ActionSelect); err != nil {
+		return e, nil, nil, v.reject(e, err)
+	}
+	if slotCode != "" {
+		e.Slot = slotCode
+		slot, err := v.slot(slotCode)
+		if err != nil {
+			return e, nil, nil, v.reject(e, err)
+		}
+		e.Product = slot.Product
+		if slot.Disabled {
+			return e, nil, nil, v.reject(e, fmt.Errorf("%w: %v", ErrSlotDisabled, slotCode))
+		}
+		if slot.Stock == 0 {
+			return e, nil, nil, v.reject(e, fmt.Errorf("%s %w", slot.Product, ErrOutOfStock))
+		}
+		items = map[string]int{slot.Product: 1}
+	}
+	if len(items) == 0 {
+		return e, nil, nil, v.reject(e, fmt.Errorf("%w: empty basket", ErrInvalidArgument))
+	}
+
+	names := make([]string, 0, len(items))
+	for name := range items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]LineItem, len(names))
+	for i, name := range names {
+		prod, ok := v.Inventory[name]
+		if !ok {
+			return e, nil, nil, v.reject(e, fmt.Errorf("%w : %v", ErrProductNotFound, name))
+		}
+		if items[name] <= 0 {
+			return e, nil, nil, v.reject(e, fmt.Errorf("%w: quantity of %v must be positive, got %d", ErrInvalidArgument, name, items[name]))
+		}
+		if prod.Stock < items[name] {
+			return e, nil, nil, v.reject(e, fmt.Errorf("%s %w", name, ErrOutOfStock))
+		}
+		lines[i] = LineItem{Product: name, Quantity: items[name], UnitPrice: prod.Price}
+	}
+
+	e.Slots = v.drawSlots(items)
+	if slotCode != "" {
+		e.Slots = map[string]int{slotCode: 1}
+	}
+
+	return e, v.price(lines), sess, nil
+}
+
+func (v *VendingMachine) price(lines []LineItem) *Receipt {
+	receipt := &Receipt{Items: lines}
+	for _, line := range lines {
+		receipt.Subtotal += line.Quantity * line.UnitPrice
+	}
+	receipt.Total = receipt.Subtotal
+	if len(v.rules) == 0 {
+		return receipt
+	}
+
+	basket := newBasket(lines)
+	at := v.now()
+	for _, rule := range v.rules {
+		for _, d := range rule.Apply(basket, at) {
+			if d.Amount <= 0 {
+				continue
+			}
+			d.Amount = min(d.Amount, receipt.Total)
+			receipt.Discounts = append(receipt.Discounts, d)
+			receipt.Total -= d.Amount
+		}
+	}
+	return receipt
+}
+
+func clockTime(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newPricedMachine(t *testing.T, at time.Time, rules ...PricingRule) *VendingMachine {
+	t.Helper()
+	vm := NewVendingMachine(map[string]Item{
+		"Cola":  {Name: "Cola", Price: 25, Stock: 10},
+		"Chips": {Name: "Chips", Price: 35, Stock: 10},
+		"Candy": {Name: "Candy", Price: 10, Stock: 20},
+	})
+	vm.SetClock(func() time.Time { return at })
+	loadCoins(t, vm, map[int]int{1: 10, 5: 10, 10: 10})
+	registerOperator(t, vm, manager, RoleManager)
+	if err := vm.SetPricingRules(manager, rules...); err != nil {
+		t.Fatalf("SetPricingRules() unexpected error = %v", err)
+	}
+	return vm
+}
+
+func TestPricingRules(t *testing.T) {
+	afternoon := time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC)
+	evening := time.Date(2025, 1, 1, 16, 0, 0, 0, time.UTC)
+	combo := ComboRule{Products: []string{"Chips", "Cola"}, Price: 50}
+	happyHour := PercentOffRule{Product: "Candy", Percent: 10, From: 14 * time.Hour, Until: 16 * time.Hour}
+	bogo := BuyXGetYRule{Product: "Candy", Buy: 2, Free: 1}
+	lateNight := PercentOffRule{Product: "Cola", Percent: 20, From: 22 * time.Hour, Until: 2 * time.Hour}
+
+	tests := []struct {
+		name          string
+		rules         []PricingRule
+		at            time.Time
+		items         map[string]int
+		wantTotal     int
+		wantDiscounts []Discount
+	}{
+		{
+			name:      "no rules",
+			at:        afternoon,
+			items:     map[string]int{"Cola": 1, "Candy": 2},
+			wantTotal: 45,
+		},
+		{
+			name:          "combo",
+			rules:         []PricingRule{combo},
+			at:            afternoon,
+			items:         map[string]int{"Chips": 1, "Cola": 1},
+			wantTotal:     50,
+			wantDiscounts: []Discount{{Rule: "Chips + Cola for 50", Amount: 10}},
+		},
+		{
+			name:          "combo applied once per complete set",
+			rules:         []PricingRule{combo},
+			at:            afternoon,
+			items:         map[string]int{"Chips": 2, "Cola": 3},
+			wantTotal:     125,
+			wantDiscounts: []Discount{{Rule: "Chips + Cola for 50", Amount: 10}, {Rule: "Chips + Cola for 50", Amount: 10}},
+		},
+		{
+			name:      "combo incomplete",
+			rules:     []PricingRule{combo},
+			at:        afternoon,
+			items:     map[string]int{"Chips": 2},
+			wantTotal: 70,
+		},
+		{
+			name:          "happy hour",
+			rules:         []PricingRule{happyHour},
+			at:            afternoon,
+			items:         map[string]int{"Candy": 5},
+			wantTotal:     45,
+			wantDiscounts: []Discount{{Rule: "10% off Candy between 14:00 and 16:00", Amount: 5}},
+		},
+		{
+			name:      "happy hour over",
+			rules:     []PricingRule{happyHour},
+			at:        evening,
+			items:     map[string]int{"Candy": 5},
+			wantTotal: 50,
+		},
+		{
+			name:          "overnight window before midnight",
+			rules:         []PricingRule{lateNight},
+			at:            time.Date(2025, 1, 1, 23, 30, 0, 0, time.UTC),
+			items:         map[string]int{"Cola": 1},
+			wantTotal:     20,
+			wantDiscounts: []Discount{{Rule: "20% off Cola between 22:00 and 02:00", Amount: 5}},
+		},
+		{
+			name:          "overnight window after midnight",
+			rules:         []PricingRule{lateNight},
+			at:            time.Date(2025, 1, 2, 1, 0, 0, 0, time.UTC),
+			items:         map[string]int{"Cola": 1},
+			wantTotal:     20,
+			wantDiscounts: []Discount{{Rule: "20% off Cola between 22:00 and 02:00", Amount: 5}},
+		},
+		{
+			name:      "overnight window at noon",
+			rules:     []PricingRule{lateNight},
+			at:        time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+			items:     map[string]int{"Cola": 1},
+			wantTotal: 25,
+		},
+		{
+			name:      "overnight window ends at its close",
+			rules:     []PricingRule{lateNight},
+			at:        time.Date(2025, 1, 2, 2, 0, 0, 0, time.UTC),
+			items:     map[string]int{"Cola": 1},
+			wantTotal: 25,
+		},
+		{
+			name:          "buy 2 get 1 free",
+			rules:         []PricingRule{bogo},
+			at:            afternoon,
+			items:         map[string]int{"Candy": 7},
+			wantTotal:     50,
+			wantDiscounts: []Discount{{Rule: "buy 2 Candy get 1 free", Amount: 20}},
+		},
+		{
+			name:  "earlier rules claim units first",
+			rules: []PricingRule{bogo, happyHour},
+			at:    afternoon,
+			items: map[string]int{"Candy": 3},
+			// The free candy is not discounted again by the happy hour.
+			wantTotal:     20,
+			wantDiscounts: []Discount{{Rule: "buy 2 Candy get 1 free", Amount: 10}},
+		},
+		{
+			name:          "rules stack on different units",
+			rules:         []PricingRule{bogo, happyHour},
+			at:            afternoon,
+			items:         map[string]int{"Candy": 4},
+			wantTotal:     29,
+			wantDiscounts: []Discount{{Rule: "buy 2 Candy get 1 free", Amount: 10}, {Rule: "10% off Candy between 14:00 and 16:00", Amount: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newPricedMachine(t, tt.at, tt.rules...)
+			insertCredit(t, vm, 200)
+
+			receipt, err := vm.Buy(tt.items)
+			if err != nil {
+				t.Fatalf("Buy() unexpected error = %v", err)
+			}
+			if receipt.Total != tt.wantTotal {
+				t.Errorf("Buy() total = %v, want %v\n%v", receipt.Total, tt.wantTotal, receipt)
+			}
+			if !reflect.DeepEqual(receipt.Discounts, tt.wantDiscounts) {
+				t.Errorf("Buy() discounts = %v, want %v", receipt.Discounts, tt.wantDiscounts)
+			}
+			if receipt.Change.Total() != 200-tt.wantTotal {
+				t.Errorf("Buy() change = %v, want %v", receipt.Change.Total(), 200-tt.wantTotal)
+			}
+			for name, quantity := range tt.items {
+				if got, want := vm.Inventory[name].Stock, map[string]int{"Cola": 10, "Chips": 10, "Candy": 20}[name]-quantity; got != want {
+					t.Errorf("Inventory[%v].Stock = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectProductUsesPricingRules(t *testing.T) {
+	vm := newPricedMachine(t, time.Date(2025, 1, 1, 15, 0, 0, 0, time.UTC),
+		PercentOffRule{Product: "Cola", Percent: 20})
+	vm.InsertCoin(10)
+	vm.InsertCoin(10)
+
+	change, discounts, err := vm.SelectProduct("Cola")
+	if err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+	if change.Total() != 0 || vm.Balance() != 0 {
+		t.Errorf("SelectProduct() change = %v, balance = %v, want 0, 0", change, vm.Balance())
+	}
+	if want := []Discount{{Rule: "20% off Cola", Amount: 5}}; !reflect.DeepEqual(discounts, want) {
+		t.Errorf("SelectProduct() discounts = %v, want %v", discounts, want)
+	}
+}
+
+func TestServerSelectReturnsDiscounts(t *testing.T) {
+	srv, vm := newTestServer(t)
+	registerOperator(t, vm, manager, RoleManager)
+	vm.SetPricingRules(manager, PercentOffRule{Product: "Candy", Percent: 50})
+	base := startSession(t, srv)
+
+	doJSON(t, srv, http.MethodPost, base+"/coins", `{"coin": 5}`, nil)
+	status, body := doJSON(t, srv, http.MethodPost, base+"/select", `{"product": "Candy"}`, nil)
+	if status != http.StatusOK {
+		t.Fatalf("POST /select = %d %v, want 200", status, body)
+	}
+	want := []any{map[string]any{"rule": "50% off Candy", "amount": float64(5)}}
+	if !reflect.DeepEqual(body["discounts"], want) {
+		t.Errorf("POST /select discounts = %v, want %v", body["discounts"], want)
+	}
+}
+
+func TestBuyErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   map[string]int
+		wantErr error
+	}{
+		{"empty basket", map[string]int{}, ErrInvalidArgument},
+		{"unknown product", map[string]int{"Cola": 1, "Gum": 1}, ErrProductNotFound},
+		{"zero quantity", map[string]int{"Cola": 0}, ErrInvalidArgument},
+		{"not enough stock", map[string]int{"Cola": 11}, ErrOutOfStock},
+		{"insufficient balance", map[string]int{"Chips": 2}, ErrInsufficientBalance},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := newPricedMachine(t, time.Now())
+			insertCredit(t, vm, 50)
+
+			if _, err := vm.Buy(tt.items); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Buy() error = %v, want %v", err, tt.wantErr)
+			}
+			if vm.Balance() != 50 || vm.Inventory["Cola"].Stock != 10 {
+				t.Errorf("Buy() changed the machine despite failing")
+			}
+		})
+	}
+}
+
+func TestSetPricingRulesRequiresPermission(t *testing.T) {
+	vm := newOperatedMachine(t)
+	err := vm.SetPricingRules(technician, BuyXGetYRule{Product: "Cola", Buy: 1, Free: 1})
+	var permErr *PermissionError
+	if !errors.As(err, &permErr) {
+		t.Fatalf("SetPricingRules() error = %v, want *PermissionError", err)
+	}
+	if len(vm.rules) != 0 {
+		t.Errorf("SetPricingRules() installed rules despite failing")
+	}
+}
+
+func TestReplayBasketPurchase(t *testing.T) {
+	store := &MemoryEventStore{}
+	vm := newPricedMachine(t, time.Now(), ComboRule{Products: []string{"Chips", "Cola"}, Price: 50})
+	vm.UseEventStore(store)
+	vm.InsertCoin(25)
+	vm.InsertCoin(25)
+	vm.InsertCoin(10)
+	if _, err := vm.Buy(map[string]int{"Chips": 1, "Cola": 1}); err != nil {
+		t.Fatalf("Buy() unexpected error = %v", err)
+	}
+
+	events, _ := store.Events()
+	replayed, err := Replay(map[string]Item{
+		"Cola":  {Name: "Cola", Price: 25, Stock: 10},
+		"Chips": {Name: "Chips", Price: 35, Stock: 10},
+		"Candy": {Name: "Candy", Price: 10, Stock: 20},
+	}, events)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error = %v", err)
+	}
+	loadCoins(t, replayed, map[int]int{1: 10, 5: 10, 10: 10})
+	if !reflect.DeepEqual(replayed.Inventory, vm.Inventory) {
+		t.Errorf("Replay() inventory = %v, want %v", replayed.Inventory, vm.Inventory)
+	}
+	if !reflect.DeepEqual(replayed.CoinStock(), vm.CoinStock()) {
+		t.Errorf("Replay() coins = %v, want %v", replayed.CoinStock(), vm.CoinStock())
+	}
+}
+
+func TestServerPurchase(t *testing.T) {
+	srv, _ := newTestServer(t)
+	base := startSession(t, srv)
+	doJSON(t, srv, http.MethodPost, base+"/coins", `{"coin": 10}`, nil)
+	doJSON(t, srv, http.MethodPost, base+"/coins", `{"coin": 10}`, nil)
+
+	status, body := doJSON(t, srv, http.MethodPost, base+"/purchase", `{"items": {"Candy": 2}}`, nil)
+	if status != http.StatusOK || body["total"] != float64(20) {
+		t.Errorf("POST /purchase = %d %v, want 200 with total 20", status, body)
+	}
+	if status, _ := doJSON(t, srv, http.MethodPost, base+"/purchase", `{"items": {}}`, nil); status != http.StatusBadRequest {
+		t.Errorf("POST /purchase with empty basket = %d, want 400", status)
+	}
+}
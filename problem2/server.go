@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type Server struct {
+	vm  *VendingMachine
+	mux *http.ServeMux
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type coinRequest struct {
+	Coin int `json:"coin"`
+}
+
+type banknoteRequest struct {
+	Banknote int `json:"banknote"`
+}
+
+type selectRequest struct {
+	Product string `json:"product"`
+	Slot    string `json:"slot"`
+}
+
+type purchaseRequest struct {
+	Items map[string]int `json:"items"`
+}
+
+type restockRequest struct {
+	Product  string `json:"product"`
+	Quantity int    `json:"quantity"`
+}
+
+type balanceResponse struct {
+	State   string `json:"state"`
+	Balance int    `json:"balance"`
+}
+
+type selectResponse struct {
+	Product     string     `json:"product,omitempty"`
+	Slot        string     `json:"slot,omitempty"`
+	Change      Change     `json:"change"`
+	ChangeTotal int        `json:"changeTotal"`
+	Discounts   []Discount `json:"discounts,omitempty"`
+}
+
+type cancelResponse struct {
+	Refund int `json:"refund"`
+}
+
+type sessionResponse struct {
+	Session string `json:"session"`
+	State   string `json:"state"`
+	Balance int    `json:"balance"`
+}
+
+// NewServer serves the machine to kiosk front-ends. Every kiosk starts its own
+// session and inserts money under /sessions/{id}, so two kiosks never share
+// credit. The machine's default session is its physical coin slot and is only
+// reported by GET /status.
+func NewServer(vm *VendingMachine) *Server {
+	s := &Server{vm: vm, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /status", s.handleStatus)
+	s.mux.HandleFunc("GET /inventory", s.handleInventory)
+	s.mux.HandleFunc("GET /slots", s.handleSlots)
+	s.mux.HandleFunc("POST /sessions", s.handleStartSession)
+	s.mux.HandleFunc("GET /sessions/{id}", s.handleStatus)
+	s.mux.HandleFunc("POST /sessions/{id}/coins", s.handleInsertCoin)
+	s.mux.HandleFunc("POST /sessions/{id}/banknotes", s.handleInsertBanknote)
+	s.mux.HandleFunc("POST /sessions/{id}/select", s.handleSelect)
+	s.mux.HandleFunc("POST /sessions/{id}/purchase", s.handlePurchase)
+	s.mux.HandleFunc("POST /sessions/{id}/cancel", s.handleCancel)
+	s.mux.HandleFunc("DELETE /sessions/{id}", s.handleEndSession)
+	s.mux.HandleFunc("POST /admin/restock", s.handleRestock)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) session(w http.ResponseWriter, r *http.Request) (*Session, bool) {
+	id := r.PathValue("id")
+	if id == "" {
+		return &Session{vm: s.vm}, true
+	}
+	sess, err := s.vm.Session(id)
+	if err != nil {
+		writeError(w, err)
+		return nil, false
+	}
+	return sess, true
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, balanceResponse{State: s.vm.State().String(), Balance: sess.Balance()})
+}
+
+func (s *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := s.vm.StartSession()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, sessionResponse{Session: sess.ID, State: s.vm.State().String()})
+}
+
+func (s *Server) handleEndSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	refund, err := sess.End()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, cancelResponse{Refund: refund})
+}
+
+func (s *Server) handleInventory(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.vm.Products())
+}
+
+func (s *Server) handleSlots(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.vm.Slots())
+}
+
+func (s *Server) handleInsertCoin(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	var req coinRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if err := sess.InsertCoin(req.Coin); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.handleStatus(w, r)
+}
+
+func (s *Server) handleInsertBanknote(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	var req banknoteRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if err := sess.InsertBanknote(req.Banknote); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.handleStatus(w, r)
+}
+
+func (s *Server) handleSelect(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	var req selectRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	var receipt *Receipt
+	var err error
+	if req.Slot != "" {
+		receipt, err = sess.BuySlot(req.Slot)
+	} else {
+		receipt, err = sess.Buy(map[string]int{req.Product: 1})
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, selectResponse{
+		Product:     receipt.Items[0].Product,
+		Slot:        req.Slot,
+		Change:      receipt.Change,
+		ChangeTotal: receipt.Change.Total(),
+		Discounts:   receipt.Discounts,
+	})
+}
+
+func (s *Server) handlePurchase(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	var req purchaseRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	receipt, err := sess.Buy(req.Items)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, receipt)
+}
+
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, cancelResponse{Refund: sess.Cancel()})
+}
+
+func (s *Server) handleRestock(w http.ResponseWriter, r *http.Request) {
+	id, pin, ok := r.BasicAuth()
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="vending machine"`)
+		writeError(w, ErrUnauthorized)
+		return
+	}
+	var req restockRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if err := s.vm.Restock(Credentials{ID: id, PIN: pin}, req.Product, req.Quantity); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	item, _ := s.vm.Product(req.Product)
+	writeJSON(w, http.StatusOK, item)
+}
+
+func statusFor(err error) int {
+	var transitionErr *TransitionError
+	var permissionErr *PermissionError
+	switch {
+	case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrSessionClosed), errors.Is(err, ErrSlotNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrOutOfStock), errors.Is(err, ErrNoExactChange), errors.Is(err, ErrProductExists),
+		errors.Is(err, ErrSlotExists), errors.Is(err, ErrSlotDisabled), errors.Is(err, ErrSlotFull),
+		errors.Is(err, ErrPriceChanged):
+		return http.StatusConflict
+	case errors.Is(err, ErrInsufficientBalance), errors.Is(err, ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	case errors.Is(err, ErrInvalidCoin), errors.Is(err, ErrInvalidBanknote), errors.Is(err, ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.As(err, &permissionErr):
+		return http.StatusForbidden
+	case errors.As(err, &transitionErr):
+		if transitionErr.State == StateMaintenance {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: fmt.Sprintf("invalid request body: %v", err)})
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusFor(err), errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
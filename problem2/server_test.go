@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *VendingMachine) {
+	t.Helper()
+	vm := NewVendingMachine(map[string]Item{
+		"Cola":  {Name: "Cola", Price: 25, Stock: 1},
+		"Chips": {Name: "Chips", Price: 35, Stock: 0},
+		"Candy": {Name: "Candy", Price: 10, Stock: 20},
+	})
+	loadCoins(t, vm, map[int]int{5: 10, 10: 10})
+	registerOperator(t, vm, technician, RoleTechnician)
+	srv := httptest.NewServer(NewServer(vm))
+	t.Cleanup(srv.Close)
+	return srv, vm
+}
+
+func doJSON(t *testing.T, srv *httptest.Server, method, path, body string, cred *Credentials) (int, map[string]any) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	if cred != nil {
+		req.SetBasicAuth(cred.ID, cred.PIN)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var out map[string]any
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// startSession starts a kiosk session and returns the path its routes live
+// under.
+func startSession(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	status, body := doJSON(t, srv, http.MethodPost, "/sessions", ``, nil)
+	id, _ := body["session"].(string)
+	if status != http.StatusCreated || id == "" {
+		t.Fatalf("POST /sessions = %d %v, want 201 with a session id", status, body)
+	}
+	return "/sessions/" + id
+}
+
+func TestServerPurchaseFlow(t *testing.T) {
+	srv, _ := newTestServer(t)
+	base := startSession(t, srv)
+
+	status, body := doJSON(t, srv, http.MethodPost, base+"/coins", `{"coin": 25}`, nil)
+	if status != http.StatusOK || body["balance"] != float64(25) || body["state"] != "has credit" {
+		t.Fatalf("POST /coins = %d %v, want 200 with balance 25", status, body)
+	}
+	doJSON(t, srv, http.MethodPost, base+"/coins", `{"coin": 10}`, nil)
+
+	status, body = doJSON(t, srv, http.MethodPost, base+"/select", `{"product": "Cola"}`, nil)
+	if status != http.StatusOK {
+		t.Fatalf("POST /select = %d %v, want 200", status, body)
+	}
+	if body["changeTotal"] != float64(10) || !reflect.DeepEqual(body["change"], map[string]any{"10": float64(1)}) {
+		t.Errorf("POST /select = %v, want change of one 10 coin", body)
+	}
+
+	doJSON(t, srv, http.MethodPost, base+"/coins", `{"coin": 5}`, nil)
+	status, body = doJSON(t, srv, http.MethodPost, base+"/cancel", ``, nil)
+	if status != http.StatusOK || body["refund"] != float64(5) {
+		t.Errorf("POST /cancel = %d %v, want refund 5", status, body)
+	}
+}
+
+func TestServerInventory(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	resp, err := srv.Client().Get(srv.URL + "/inventory")
+	if err != nil {
+		t.Fatalf("GET /inventory failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var items []Item
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		t.Fatalf("GET /inventory invalid body: %v", err)
+	}
+	want := []Item{
+		{Name: "Candy", Price: 10, Stock: 20},
+		{Name: "Chips", Price: 35, Stock: 0},
+		{Name: "Cola", Price: 25, Stock: 1},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("GET /inventory = %v, want %v", items, want)
+	}
+}
+
+func TestServerErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(vm *VendingMachine, sess *Session)
+		method     string
+		path       string
+		body       string
+		cred       *Credentials
+		wantStatus int
+	}{
+		{"unknown product", nil, http.MethodPost, "/select", `{"product": "Gum"}`, nil, http.StatusNotFound},
+		{"out of stock", nil, http.MethodPost, "/select", `{"product": "Chips"}`, nil, http.StatusConflict},
+		{"insufficient balance", nil, http.MethodPost, "/select", `{"product": "Cola"}`, nil, http.StatusPaymentRequired},
+		{"invalid coin", nil, http.MethodPost, "/coins", `{"coin": 3}`, nil, http.StatusBadRequest},
+		{"malformed body", nil, http.MethodPost, "/coins", `{"coin": "25"}`, nil, http.StatusBadRequest},
+		{"unknown field", nil, http.MethodPost, "/coins", `{"value": 25}`, nil, http.StatusBadRequest},
+		{"wrong method", nil, http.MethodGet, "/select", ``, nil, http.StatusMethodNotAllowed},
+		{"no exact change", func(vm *VendingMachine, sess *Session) {
+			vm.EmptyCoins(admin)
+			sess.InsertCoin(25)
+			sess.InsertCoin(1)
+		}, http.MethodPost, "/select", `{"product": "Candy"}`, nil, http.StatusConflict},
+		{"maintenance", func(vm *VendingMachine, sess *Session) {
+			vm.LockForMaintenance(technician)
+		}, http.MethodPost, "/coins", `{"coin": 25}`, nil, http.StatusServiceUnavailable},
+		{"restock without credentials", nil, http.MethodPost, "/admin/restock", `{"product": "Cola", "quantity": 2}`, nil, http.StatusUnauthorized},
+		{"restock with wrong pin", nil, http.MethodPost, "/admin/restock", `{"product": "Cola", "quantity": 2}`, &Credentials{ID: "tina", PIN: "0"}, http.StatusUnauthorized},
+		{"restock unknown product", nil, http.MethodPost, "/admin/restock", `{"product": "Gum", "quantity": 2}`, &technician, http.StatusNotFound},
+		{"restock invalid quantity", nil, http.MethodPost, "/admin/restock", `{"product": "Cola", "quantity": 0}`, &technician, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, vm := newTestServer(t)
+			base := startSession(t, srv)
+			if tt.setup != nil {
+				sess, err := vm.Session(strings.TrimPrefix(base, "/sessions/"))
+				if err != nil {
+					t.Fatalf("Session() unexpected error = %v", err)
+				}
+				tt.setup(vm, sess)
+			}
+			path := tt.path
+			if !strings.HasPrefix(path, "/admin/") {
+				path = base + path
+			}
+			status, body := doJSON(t, srv, tt.method, path, tt.body, tt.cred)
+			if status != tt.wantStatus {
+				t.Errorf("%s %s = %d %v, want %d", tt.method, tt.path, status, body, tt.wantStatus)
+			}
+			if status != http.StatusMethodNotAllowed && body["error"] == nil {
+				t.Errorf("%s %s body = %v, want an error message", tt.method, tt.path, body)
+			}
+		})
+	}
+}
+
+func TestServerRestock(t *testing.T) {
+	srv, vm := newTestServer(t)
+
+	status, body := doJSON(t, srv, http.MethodPost, "/admin/restock", `{"product": "Chips", "quantity": 6}`, &technician)
+	if status != http.StatusOK || body["stock"] != float64(6) {
+		t.Fatalf("POST /admin/restock = %d %v, want 200 with stock 6", status, body)
+	}
+	if vm.Inventory["Chips"].Stock != 6 {
+		t.Errorf("Inventory[Chips].Stock = %v, want 6", vm.Inventory["Chips"].Stock)
+	}
+
+	registerOperator(t, vm, Credentials{ID: "pat", PIN: "1"}, RoleTechnician)
+	status, _ = doJSON(t, srv, http.MethodPost, "/admin/restock", `{"product": "Chips", "quantity": 1}`, &Credentials{ID: "pat", PIN: "1"})
+	if status != http.StatusOK {
+		t.Errorf("POST /admin/restock by second technician = %d, want 200", status)
+	}
+}
+
+func TestStatusForPermissionError(t *testing.T) {
+	if got := statusFor(&PermissionError{}); got != http.StatusForbidden {
+		t.Errorf("statusFor(*PermissionError) = %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func TestServerKiosksDoNotShareCredit(t *testing.T) {
+	srv, _ := newTestServer(t)
+	first := startSession(t, srv)
+	second := startSession(t, srv)
+
+	doJSON(t, srv, http.MethodPost, first+"/coins", `{"coin": 10}`, nil)
+	doJSON(t, srv, http.MethodPost, second+"/coins", `{"coin": 10}`, nil)
+	doJSON(t, srv, http.MethodPost, second+"/coins", `{"coin": 10}`, nil)
+
+	if status, body := doJSON(t, srv, http.MethodPost, first+"/select", `{"product": "Cola"}`, nil); status != http.StatusPaymentRequired {
+		t.Errorf("POST %s/select = %d %v, want 402 with only the first kiosk's 10", first, status, body)
+	}
+	if _, body := doJSON(t, srv, http.MethodPost, second+"/cancel", ``, nil); body["refund"] != float64(20) {
+		t.Errorf("POST %s/cancel refund = %v, want 20", second, body["refund"])
+	}
+	if _, body := doJSON(t, srv, http.MethodGet, first, ``, nil); body["balance"] != float64(10) {
+		t.Errorf("GET %s balance = %v, want 10", first, body["balance"])
+	}
+
+	for _, path := range []string{"/coins", "/banknotes", "/select", "/purchase", "/cancel"} {
+		if status, _ := doJSON(t, srv, http.MethodPost, path, `{}`, nil); status != http.StatusNotFound {
+			t.Errorf("POST %s without a session = %d, want 404", path, status)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const DefaultSessionTimeout = 2 * time.Minute
+
+var ErrSessionClosed = errors.New("session closed")
+
+type Session struct {
+	ID string
+	vm *VendingMachine
+}
+
+type session struct {
+	balance    int
+	inserted   map[int]int
+	notes      map[int]int
+	lastActive time.Time
+}
+
+func newSession(at time.Time) *session {
+	return &session{inserted: make(map[int]int), notes: make(map[int]int), lastActive: at}
+}
+
+func (v *VendingMachine) StartSession() (*Session, error) {
+	v.Lock()
+	defer v.Unlock()
+	v.expireSessions()
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	if err := v.commit(Event{Type: EventStartSession, Session: id}); err != nil {
+		return nil, err
+	}
+	return &Session{ID: id, vm: v}, nil
+}
+
+func (v *VendingMachine) Session(id string) (*Session, error) {
+	v.Lock()
+	defer v.Unlock()
+	v.expireSessions()
+	if _, ok := v.sessions[id]; !ok {
+		return nil, fmt.Errorf("%w: %v", ErrSessionClosed, id)
+	}
+	return &Session{ID: id, vm: v}, nil
+}
+
+func (v *VendingMachine) SetSessionTimeout(d time.Duration) {
+	v.Lock()
+	defer v.Unlock()
+	v.sessionTimeout = d
+}
+
+func (s *Session) InsertCoin(coinValue int) error {
+	return s.vm.insertCash(s.ID, EventInsertCoin, coinValue)
+}
+
+func (s *Session) InsertBanknote(value int) error {
+	return s.vm.insertCash(s.ID, EventInsertBanknote, value)
+}
+
+func (s *Session) SelectProduct(productName string) (Change, []Discount, error) {
+	return s.vm.selectProduct(s.ID, productName)
+}
+
+func (s *Session) Cancel() int {
+	return s.vm.cancel(s.ID)
+}
+
+func (s *Session) Balance() int {
+	return s.vm.balance(s.ID)
+}
+
+// End refunds any credit left in the session and closes it. The machine's
+// default session cannot be ended.
+func (s *Session) End() (int, error) {
+	v := s.vm
+	v.Lock()
+	defer v.Unlock()
+	v.expireSessions()
+	sess, err := v.session(s.ID)
+	if err != nil {
+		return 0, err
+	}
+	if s.ID == "" {
+		return 0, fmt.Errorf("%w: the default session cannot be ended", ErrInvalidArgument)
+	}
+	from := v.state()
+	e := Event{Type: EventEndSession, Session: s.ID, Amount: sess.balance}
+	v.settle(&e)
+	v.notify(from, ActionCancel)
+	return e.Amount, nil
+}
+
+// ExpireSessions ends every session that has been idle for longer than the
+// session timeout and returns the credit refunded to them. Expiry also
+// happens lazily on every session operation, so calling it is only needed to
+// hand back coins when the machine is otherwise unused.
+func (v *VendingMachine) ExpireSessions() int {
+	v.Lock()
+	defer v.Unlock()
+	return v.expireSessions()
+}
+
+func (v *VendingMachine) RunSessionReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			v.ExpireSessions()
+		}
+	}
+}
+
+// The default session stands for the coin slot on the machine itself and is
+// never expired.
+func (v *VendingMachine) expireSessions() int {
+	if v.sessionTimeout <= 0 || len(v.sessions) <= 1 {
+		return 0
+	}
+	now := v.now()
+	var expired []string
+	for id, sess := range v.sessions {
+		if id != "" && now.Sub(sess.lastActive) >= v.sessionTimeout {
+			expired = append(expired, id)
+		}
+	}
+	sort.Strings(expired)
+
+	refunded := 0
+	for _, id := range expired {
+		from := v.state()
+		e := Event{Type: EventExpireSession, Session: id, Amount: v.sessions[id].balance}
+		v.settle(&e)
+		v.notify(from, ActionCancel)
+		refunded += e.Amount
+	}
+	return refunded
+}
+
+func (v *VendingMachine) session(id string) (*session, error) {
+	sess, ok := v.sessions[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrSessionClosed, id)
+	}
+	return sess, nil
+}
+
+func (v *VendingMachine) hasCredit() bool {
+	for _, sess := range v.sessions {
+		if sess.balance > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSessionsKeepSeparateCredit(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Cola": {Name: "Cola", Price: 25, Stock: 1}})
+	loadCoins(t, vm, map[int]int{5: 4})
+
+	alice, err := vm.StartSession()
+	if err != nil {
+		t.Fatalf("StartSession() unexpected error = %v", err)
+	}
+	bob, _ := vm.StartSession()
+	if alice.ID == bob.ID || alice.ID == "" {
+		t.Fatalf("StartSession() ids = %q and %q, want distinct non-empty ids", alice.ID, bob.ID)
+	}
+
+	alice.InsertCoin(10)
+	alice.InsertCoin(10)
+	bob.InsertCoin(25)
+	if alice.Balance() != 20 || bob.Balance() != 25 || vm.Balance() != 0 {
+		t.Fatalf("Balance() = %d/%d/%d, want 20/25/0", alice.Balance(), bob.Balance(), vm.Balance())
+	}
+
+	if _, _, err := alice.SelectProduct("Cola"); !errors.Is(err, ErrInsufficientBalance) {
+		t.Errorf("SelectProduct() error = %v, want %v despite another session's credit", err, ErrInsufficientBalance)
+	}
+	if _, _, err := bob.SelectProduct("Cola"); err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+	if bob.Balance() != 0 || alice.Balance() != 20 {
+		t.Errorf("Balance() after purchase = %d/%d, want 0/20", bob.Balance(), alice.Balance())
+	}
+
+	alice.InsertCoin(5)
+	if _, _, err := alice.SelectProduct("Cola"); !errors.Is(err, ErrOutOfStock) {
+		t.Errorf("SelectProduct() error = %v, want %v from the shared stock", err, ErrOutOfStock)
+	}
+	if refund := alice.Cancel(); refund != 25 {
+		t.Errorf("Cancel() refund = %v, want 25", refund)
+	}
+	if vm.State() != StateIdle {
+		t.Errorf("State() = %v, want %v once no session holds credit", vm.State(), StateIdle)
+	}
+}
+
+func TestSessionChangeExcludesOtherSessionsCoins(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Candy": {Name: "Candy", Price: 10, Stock: 5}})
+	alice, _ := vm.StartSession()
+	bob, _ := vm.StartSession()
+
+	alice.InsertCoin(5)
+	bob.InsertCoin(10)
+	bob.InsertCoin(5)
+	if _, _, err := bob.SelectProduct("Candy"); err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+
+	alice.InsertCoin(10)
+	if _, _, err := alice.SelectProduct("Candy"); err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+	if got, want := vm.CoinStock(), (Change{10: 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("CoinStock() = %v, want %v", got, want)
+	}
+}
+
+func TestSessionEnd(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{})
+	sess, _ := vm.StartSession()
+	sess.InsertCoin(25)
+
+	refund, err := sess.End()
+	if err != nil || refund != 25 {
+		t.Fatalf("End() = %v, %v, want 25, nil", refund, err)
+	}
+	if err := sess.InsertCoin(5); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("InsertCoin() after End() error = %v, want %v", err, ErrSessionClosed)
+	}
+	if _, err := sess.End(); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("End() twice error = %v, want %v", err, ErrSessionClosed)
+	}
+	if _, err := vm.Session(sess.ID); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("Session() error = %v, want %v", err, ErrSessionClosed)
+	}
+	if _, err := (&Session{vm: vm}).End(); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("End() of the default session error = %v, want %v", err, ErrInvalidArgument)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	vm := NewVendingMachine(map[string]Item{})
+	vm.SetClock(func() time.Time { return now })
+	vm.SetSessionTimeout(time.Minute)
+	store := &MemoryEventStore{}
+	vm.UseEventStore(store)
+
+	idle, _ := vm.StartSession()
+	active, _ := vm.StartSession()
+	idle.InsertCoin(25)
+	vm.InsertCoin(10)
+
+	now = now.Add(45 * time.Second)
+	active.InsertCoin(5)
+
+	now = now.Add(30 * time.Second)
+	if refunded := vm.ExpireSessions(); refunded != 25 {
+		t.Errorf("ExpireSessions() = %v, want 25", refunded)
+	}
+	if err := idle.InsertCoin(5); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("InsertCoin() on expired session error = %v, want %v", err, ErrSessionClosed)
+	}
+	if active.Balance() != 5 {
+		t.Errorf("Balance() of active session = %v, want 5", active.Balance())
+	}
+	if vm.Balance() != 10 {
+		t.Errorf("Balance() of default session = %v, want 10 as it never expires", vm.Balance())
+	}
+
+	now = now.Add(time.Minute)
+	if active.Balance() != 0 {
+		t.Errorf("Balance() = %v, want 0 after lazy expiry", active.Balance())
+	}
+
+	events, _ := store.Events()
+	replayed, err := Replay(map[string]Item{}, events)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error = %v", err)
+	}
+	if len(replayed.sessions) != 1 || replayed.Balance() != 10 {
+		t.Errorf("Replay() sessions = %d with balance %d, want only the default with 10", len(replayed.sessions), replayed.Balance())
+	}
+}
+
+func TestLockForMaintenanceRefundsEverySession(t *testing.T) {
+	vm := newOperatedMachine(t)
+	sess, _ := vm.StartSession()
+	sess.InsertCoin(25)
+	vm.InsertCoin(10)
+
+	refund, err := vm.LockForMaintenance(technician)
+	if err != nil || refund != 35 {
+		t.Fatalf("LockForMaintenance() = %v, %v, want 35, nil", refund, err)
+	}
+	if sess.Balance() != 0 || vm.State() != StateMaintenance {
+		t.Errorf("after LockForMaintenance() balance = %v, state = %v", sess.Balance(), vm.State())
+	}
+}
+
+func TestSessionsConcurrent(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Candy": {Name: "Candy", Price: 10, Stock: 100}})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sess, err := vm.StartSession()
+			if err != nil {
+				t.Errorf("StartSession() unexpected error = %v", err)
+				return
+			}
+			for j := 0; j < 5; j++ {
+				sess.InsertCoin(10)
+				if _, _, err := sess.SelectProduct("Candy"); err != nil {
+					t.Errorf("SelectProduct() unexpected error = %v", err)
+				}
+			}
+			sess.End()
+		}()
+	}
+	wg.Wait()
+
+	if got := vm.Inventory["Candy"].Stock; got != 50 {
+		t.Errorf("Inventory[Candy].Stock = %v, want 50", got)
+	}
+	if got := vm.CoinStock().Total(); got != 500 {
+		t.Errorf("CoinStock().Total() = %v, want 500", got)
+	}
+}
+
+func TestServerSessions(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	status, body := doJSON(t, srv, http.MethodPost, "/sessions", ``, nil)
+	id, _ := body["session"].(string)
+	if status != http.StatusCreated || id == "" {
+		t.Fatalf("POST /sessions = %d %v, want 201 with a session id", status, body)
+	}
+
+	doJSON(t, srv, http.MethodPost, "/sessions/"+id+"/coins", `{"coin": 25}`, nil)
+	if _, body := doJSON(t, srv, http.MethodGet, "/status", ``, nil); body["balance"] != float64(0) {
+		t.Errorf("GET /status balance = %v, want 0 for the default session", body["balance"])
+	}
+	if _, body := doJSON(t, srv, http.MethodGet, "/sessions/"+id, ``, nil); body["balance"] != float64(25) {
+		t.Errorf("GET /sessions/{id} balance = %v, want 25", body["balance"])
+	}
+
+	if status, body := doJSON(t, srv, http.MethodPost, "/sessions/"+id+"/select", `{"product": "Cola"}`, nil); status != http.StatusOK {
+		t.Errorf("POST /sessions/{id}/select = %d %v, want 200", status, body)
+	}
+	doJSON(t, srv, http.MethodPost, "/sessions/"+id+"/coins", `{"coin": 10}`, nil)
+	if status, body := doJSON(t, srv, http.MethodDelete, "/sessions/"+id, ``, nil); status != http.StatusOK || body["refund"] != float64(10) {
+		t.Errorf("DELETE /sessions/{id} = %d %v, want refund 10", status, body)
+	}
+	if status, _ := doJSON(t, srv, http.MethodPost, "/sessions/"+id+"/coins", `{"coin": 10}`, nil); status != http.StatusNotFound {
+		t.Errorf("POST /sessions/{id}/coins after end = %d, want 404", status)
+	}
+}
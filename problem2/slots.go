@@ -0,0 +1,268 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	ErrSlotNotFound = errors.New("no slot found with code")
+	ErrSlotExists   = errors.New("slot already exists")
+	ErrSlotDisabled = errors.New("slot is disabled")
+	ErrSlotFull     = errors.New("not enough slot capacity")
+)
+
+// Once a product is assigned to slots, its Item.Stock is the stock of its
+// enabled slots, so a jammed slot stops selling without taking the product
+// off sale.
+type Slot struct {
+	Code     string `json:"code"`
+	Product  string `json:"product"`
+	Capacity int    `json:"capacity"`
+	Stock    int    `json:"stock"`
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+func (v *VendingMachine) Slots() []Slot {
+	v.Lock()
+	defer v.Unlock()
+	slots := make([]Slot, 0, len(v.slots))
+	for _, slot := range v.slots {
+		slots = append(slots, *slot)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Code < slots[j].Code })
+	return slots
+}
+
+// AssignSlot adds an empty slot for a product. The first slot of a product
+// takes over the stock it already had.
+func (v *VendingMachine) AssignSlot(cred Credentials, code, productName string, capacity int) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventAssignSlot, Operator: cred.ID, Slot: code, Product: productName, Quantity: capacity}
+	if err := v.authorize(cred, PermManageCatalogue); err != nil {
+		return v.reject(e, err)
+	}
+	if code == "" || capacity <= 0 {
+		return v.reject(e, fmt.Errorf("%w: slot code and a positive capacity are required", ErrInvalidArgument))
+	}
+	if _, ok := v.slots[code]; ok {
+		return v.reject(e, fmt.Errorf("%w: %v", ErrSlotExists, code))
+	}
+	prod, ok := v.Inventory[productName]
+	if !ok {
+		return v.reject(e, fmt.Errorf("%w : %v", ErrProductNotFound, productName))
+	}
+	if len(v.slotsFor(productName)) == 0 && prod.Stock > capacity {
+		return v.reject(e, fmt.Errorf("%w: %d %v do not fit in slot %v", ErrSlotFull, prod.Stock, productName, code))
+	}
+	return v.commit(e)
+}
+
+func (v *VendingMachine) RestockSlot(cred Credentials, code string, quantity int) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: EventRestock, Operator: cred.ID, Slot: code, Quantity: quantity}
+	if err := v.authorize(cred, PermRestock); err != nil {
+		return v.reject(e, err)
+	}
+	slot, err := v.slot(code)
+	if err != nil {
+		return v.reject(e, err)
+	}
+	e.Product = slot.Product
+	if quantity <= 0 {
+		return v.reject(e, fmt.Errorf("%w: restock quantity must be positive, got %d", ErrInvalidArgument, quantity))
+	}
+	if slot.Stock+quantity > slot.Capacity {
+		return v.reject(e, fmt.Errorf("%w: slot %v has room for %d", ErrSlotFull, code, slot.Capacity-slot.Stock))
+	}
+	e.Slots = map[string]int{code: quantity}
+	return v.commit(e)
+}
+
+func (v *VendingMachine) DisableSlot(cred Credentials, code string) error {
+	return v.setSlotDisabled(cred, code, EventDisableSlot)
+}
+
+func (v *VendingMachine) EnableSlot(cred Credentials, code string) error {
+	return v.setSlotDisabled(cred, code, EventEnableSlot)
+}
+
+func (v *VendingMachine) setSlotDisabled(cred Credentials, code string, kind EventType) error {
+	v.Lock()
+	defer v.Unlock()
+	e := Event{Type: kind, Operator: cred.ID, Slot: code}
+	if err := v.authorize(cred, PermMaintenance); err != nil {
+		return v.reject(e, err)
+	}
+	slot, err := v.slot(code)
+	if err != nil {
+		return v.reject(e, err)
+	}
+	e.Product = slot.Product
+	return v.commit(e)
+}
+
+func (v *VendingMachine) SelectSlot(code string) (Change, []Discount, error) {
+	return v.selectSlot("", code)
+}
+
+func (s *Session) SelectSlot(code string) (Change, []Discount, error) {
+	return s.vm.selectSlot(s.ID, code)
+}
+
+// BuySlot sells one item from a slot like SelectSlot, returning the receipt
+// so that callers learn which product the slot held.
+func (v *VendingMachine) BuySlot(code string) (*Receipt, error) {
+	return v.buy("", nil, nil, code)
+}
+
+func (s *Session) BuySlot(code string) (*Receipt, error) {
+	return s.vm.buy(s.ID, nil, nil, code)
+}
+
+func (v *VendingMachine) selectSlot(id, code string) (Change, []Discount, error) {
+	receipt, err := v.buy(id, nil, nil, code)
+	if err != nil {
+		return nil, nil, err
+	}
+	return receipt.Change, receipt.Discounts, nil
+}
+
+func (v *VendingMachine) slot(code string) (*Slot, error) {
+	slot, ok := v.slots[code]
+	if !ok {
+		return nil, fmt.Errorf("%w : %v", ErrSlotNotFound, code)
+	}
+	return slot, nil
+}
+
+func (v *VendingMachine) slotsFor(productName string) []*Slot {
+	var slots []*Slot
+	for _, slot := range v.slots {
+		if slot.Product == productName {
+			slots = append(slots, slot)
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Code < slots[j].Code })
+	return slots
+}
+
+// drawSlots picks the slots a sale is dispensed from, taking each unit from
+// the fullest enabled slot of its product. Products without slots are left
+// out.
+func (v *VendingMachine) drawSlots(items map[string]int) map[string]int {
+	drawn := make(map[string]int)
+	for name, quantity := range items {
+		slots := v.slotsFor(name)
+		for i := 0; i < quantity; i++ {
+			var fullest *Slot
+			for _, slot := range slots {
+				left := slot.Stock - drawn[slot.Code]
+				if !slot.Disabled && left > 0 && (fullest == nil || left > fullest.Stock-drawn[fullest.Code]) {
+					fullest = slot
+				}
+			}
+			if fullest == nil {
+				break
+			}
+			drawn[fullest.Code]++
+		}
+	}
+	if len(drawn) == 0 {
+		return nil
+	}
+	return drawn
+}
+
+// fillSlots spreads a restock over the enabled slots of a product, filling the
+// emptiest slot first.
+func (v *VendingMachine) fillSlots(productName string, quantity int) (map[string]int, error) {
+	slots := v.slotsFor(productName)
+	filled := make(map[string]int)
+	for i := 0; i < quantity; i++ {
+		var emptiest *Slot
+		for _, slot := range slots {
+			stock := slot.Stock + filled[slot.Code]
+			if !slot.Disabled && stock < slot.Capacity && (emptiest == nil || stock < emptiest.Stock+filled[emptiest.Code]) {
+				emptiest = slot
+			}
+		}
+		if emptiest == nil {
+			return nil, fmt.Errorf("%w: the slots of %v have room for %d", ErrSlotFull, productName, i)
+		}
+		filled[emptiest.Code]++
+	}
+	return filled, nil
+}
+
+func (v *VendingMachine) applySlots(e Event) error {
+	switch e.Type {
+	case EventAssignSlot:
+		if _, ok := v.slots[e.Slot]; ok {
+			return fmt.Errorf("%w: %v", ErrSlotExists, e.Slot)
+		}
+		prod, ok := v.Inventory[e.Product]
+		if !ok {
+			return fmt.Errorf("%w : %v", ErrProductNotFound, e.Product)
+		}
+		slot := &Slot{Code: e.Slot, Product: e.Product, Capacity: e.Quantity}
+		if len(v.slotsFor(e.Product)) == 0 {
+			slot.Stock = prod.Stock
+		}
+		if slot.Stock > slot.Capacity {
+			return fmt.Errorf("%w: %d %v do not fit in slot %v", ErrSlotFull, slot.Stock, e.Product, e.Slot)
+		}
+		v.slots[e.Slot] = slot
+	case EventDisableSlot, EventEnableSlot:
+		slot, err := v.slot(e.Slot)
+		if err != nil {
+			return err
+		}
+		slot.Disabled = e.Type == EventDisableSlot
+	}
+	v.syncSlotStock(e.Product)
+	return nil
+}
+
+func (v *VendingMachine) moveSlotStock(counts map[string]int, restock bool) error {
+	for code, n := range counts {
+		slot, err := v.slot(code)
+		if err != nil {
+			return err
+		}
+		if restock && slot.Stock+n > slot.Capacity {
+			return fmt.Errorf("%w: slot %v has room for %d", ErrSlotFull, code, slot.Capacity-slot.Stock)
+		}
+		if !restock && slot.Stock < n {
+			return fmt.Errorf("cannot dispense %d from slot %v", n, code)
+		}
+	}
+	for code, n := range counts {
+		if restock {
+			v.slots[code].Stock += n
+		} else {
+			v.slots[code].Stock -= n
+		}
+	}
+	return nil
+}
+
+func (v *VendingMachine) syncSlotStock(productName string) {
+	slots := v.slotsFor(productName)
+	prod, ok := v.Inventory[productName]
+	if len(slots) == 0 || !ok {
+		return
+	}
+	before := prod.Stock
+	prod.Stock = 0
+	for _, slot := range slots {
+		if !slot.Disabled {
+			prod.Stock += slot.Stock
+		}
+	}
+	v.Inventory[productName] = prod
+	v.checkLowStock(productName, before)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func newSlottedMachine(t *testing.T) *VendingMachine {
+	t.Helper()
+	vm := newOperatedMachine(t)
+	vm.AddProduct(manager, Item{Name: "Chips", Price: 35})
+	for _, s := range []struct {
+		code, product string
+		capacity      int
+	}{
+		{"A1", "Cola", 8},
+		{"A2", "Cola", 8},
+		{"B1", "Chips", 5},
+	} {
+		if err := vm.AssignSlot(manager, s.code, s.product, s.capacity); err != nil {
+			t.Fatalf("AssignSlot(%v) unexpected error = %v", s.code, err)
+		}
+	}
+	vm.RestockSlot(technician, "A2", 3)
+	vm.RestockSlot(technician, "B1", 2)
+	return vm
+}
+
+func TestAssignSlot(t *testing.T) {
+	vm := newSlottedMachine(t)
+
+	want := []Slot{
+		{Code: "A1", Product: "Cola", Capacity: 8, Stock: 1},
+		{Code: "A2", Product: "Cola", Capacity: 8, Stock: 3},
+		{Code: "B1", Product: "Chips", Capacity: 5, Stock: 2},
+	}
+	if got := vm.Slots(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Slots() = %v, want %v", got, want)
+	}
+	if vm.Inventory["Cola"].Stock != 4 {
+		t.Errorf("Inventory[Cola].Stock = %v, want 4 across both slots", vm.Inventory["Cola"].Stock)
+	}
+
+	tests := []struct {
+		name     string
+		cred     Credentials
+		code     string
+		product  string
+		capacity int
+		wantErr  error
+	}{
+		{"duplicate code", manager, "A1", "Cola", 5, ErrSlotExists},
+		{"unknown product", manager, "C1", "Gum", 5, ErrProductNotFound},
+		{"zero capacity", manager, "C1", "Cola", 0, ErrInvalidArgument},
+		{"technician", technician, "C1", "Cola", 5, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := vm.AssignSlot(tt.cred, tt.code, tt.product, tt.capacity)
+			if tt.wantErr == nil {
+				var permErr *PermissionError
+				if !errors.As(err, &permErr) {
+					t.Errorf("AssignSlot() error = %v, want *PermissionError", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("AssignSlot() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssignSlotTooSmallForStock(t *testing.T) {
+	vm := newOperatedMachine(t)
+	vm.Restock(technician, "Cola", 9)
+	if err := vm.AssignSlot(manager, "A1", "Cola", 5); !errors.Is(err, ErrSlotFull) {
+		t.Errorf("AssignSlot() error = %v, want %v", err, ErrSlotFull)
+	}
+}
+
+func TestSelectFromSlots(t *testing.T) {
+	vm := newSlottedMachine(t)
+
+	insertCredit(t, vm, 25)
+	if _, _, err := vm.SelectProduct("Cola"); err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+	insertCredit(t, vm, 25)
+	if _, _, err := vm.SelectSlot("A1"); err != nil {
+		t.Fatalf("SelectSlot() unexpected error = %v", err)
+	}
+
+	want := map[string]int{"A1": 0, "A2": 2, "B1": 2}
+	for _, slot := range vm.Slots() {
+		if slot.Stock != want[slot.Code] {
+			t.Errorf("slot %v stock = %v, want %v", slot.Code, slot.Stock, want[slot.Code])
+		}
+	}
+	if vm.Inventory["Cola"].Stock != 2 {
+		t.Errorf("Inventory[Cola].Stock = %v, want 2", vm.Inventory["Cola"].Stock)
+	}
+
+	insertCredit(t, vm, 25)
+	if _, _, err := vm.SelectSlot("A1"); !errors.Is(err, ErrOutOfStock) {
+		t.Errorf("SelectSlot() on empty slot error = %v, want %v", err, ErrOutOfStock)
+	}
+	if _, _, err := vm.SelectSlot("Z9"); !errors.Is(err, ErrSlotNotFound) {
+		t.Errorf("SelectSlot() on unknown slot error = %v, want %v", err, ErrSlotNotFound)
+	}
+	if vm.Balance() != 25 {
+		t.Errorf("Balance() = %v, want the credit kept after failed selections", vm.Balance())
+	}
+}
+
+func TestBuyDrawsFromFullestSlot(t *testing.T) {
+	vm := newSlottedMachine(t)
+	vm.RestockSlot(technician, "A1", 2)
+	insertCredit(t, vm, 100)
+
+	if _, err := vm.Buy(map[string]int{"Cola": 4}); err != nil {
+		t.Fatalf("Buy() unexpected error = %v", err)
+	}
+	for _, slot := range vm.Slots() {
+		if slot.Product == "Cola" && slot.Stock != 1 {
+			t.Errorf("slot %v stock = %v, want 1 as sales even out the slots", slot.Code, slot.Stock)
+		}
+	}
+}
+
+func TestJammedSlot(t *testing.T) {
+	vm := newSlottedMachine(t)
+
+	if err := vm.DisableSlot(technician, "A2"); err != nil {
+		t.Fatalf("DisableSlot() unexpected error = %v", err)
+	}
+	if vm.Inventory["Cola"].Stock != 1 {
+		t.Errorf("Inventory[Cola].Stock = %v, want 1 with A2 jammed", vm.Inventory["Cola"].Stock)
+	}
+
+	insertCredit(t, vm, 25)
+	if _, _, err := vm.SelectSlot("A2"); !errors.Is(err, ErrSlotDisabled) {
+		t.Errorf("SelectSlot() error = %v, want %v", err, ErrSlotDisabled)
+	}
+	if _, _, err := vm.SelectProduct("Cola"); err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v with another slot working", err)
+	}
+	insertCredit(t, vm, 25)
+	if _, _, err := vm.SelectProduct("Cola"); !errors.Is(err, ErrOutOfStock) {
+		t.Errorf("SelectProduct() error = %v, want %v", err, ErrOutOfStock)
+	}
+	if _, ok := vm.Inventory["Cola"]; !ok {
+		t.Errorf("DisableSlot() removed the product from the inventory")
+	}
+
+	vm.EnableSlot(technician, "A2")
+	if _, _, err := vm.SelectProduct("Cola"); err != nil {
+		t.Errorf("SelectProduct() unexpected error = %v after the slot is fixed", err)
+	}
+}
+
+func TestJammedSlotTriggersLowStockAlert(t *testing.T) {
+	vm := newSlottedMachine(t)
+	var alerts []Item
+	vm.SetLowStockAlert(3, func(item Item) { alerts = append(alerts, item) })
+
+	vm.DisableSlot(technician, "A2")
+	vm.EnableSlot(technician, "A2")
+	vm.DisableSlot(technician, "A2")
+
+	want := []Item{{Name: "Cola", Price: 25, Stock: 1}, {Name: "Cola", Price: 25, Stock: 1}}
+	if !reflect.DeepEqual(alerts, want) {
+		t.Errorf("low stock alerts = %v, want %v", alerts, want)
+	}
+}
+
+func TestRestockRespectsCapacity(t *testing.T) {
+	vm := newSlottedMachine(t)
+
+	if err := vm.RestockSlot(technician, "B1", 4); !errors.Is(err, ErrSlotFull) {
+		t.Errorf("RestockSlot() error = %v, want %v", err, ErrSlotFull)
+	}
+	if err := vm.Restock(technician, "Cola", 13); !errors.Is(err, ErrSlotFull) {
+		t.Errorf("Restock() error = %v, want %v", err, ErrSlotFull)
+	}
+	if err := vm.Restock(technician, "Cola", 6); err != nil {
+		t.Fatalf("Restock() unexpected error = %v", err)
+	}
+
+	want := map[string]int{"A1": 5, "A2": 5, "B1": 2}
+	for _, slot := range vm.Slots() {
+		if slot.Stock != want[slot.Code] {
+			t.Errorf("slot %v stock = %v, want %v", slot.Code, slot.Stock, want[slot.Code])
+		}
+	}
+	if vm.Inventory["Cola"].Stock != 10 {
+		t.Errorf("Inventory[Cola].Stock = %v, want 10", vm.Inventory["Cola"].Stock)
+	}
+}
+
+func TestSlotsReplayAndSnapshot(t *testing.T) {
+	store := &MemoryEventStore{}
+	vm := newOperatedMachine(t)
+	vm.UseEventStore(store)
+	vm.AssignSlot(manager, "A1", "Cola", 4)
+	vm.AssignSlot(manager, "A2", "Cola", 4)
+	vm.Restock(technician, "Cola", 5)
+	vm.DisableSlot(technician, "A1")
+	insertCredit(t, vm, 25)
+	vm.SelectProduct("Cola")
+
+	events, _ := store.Events()
+	replayed, err := Replay(map[string]Item{"Cola": {Name: "Cola", Price: 25, Stock: 1}}, events)
+	if err != nil {
+		t.Fatalf("Replay() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(replayed.Slots(), vm.Slots()) {
+		t.Errorf("Replay() slots = %v, want %v", replayed.Slots(), vm.Slots())
+	}
+
+	var buf bytes.Buffer
+	vm.Save(&buf)
+	restored := NewVendingMachine(map[string]Item{})
+	if err := restored.Load(&buf); err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if !reflect.DeepEqual(restored.Slots(), vm.Slots()) || !reflect.DeepEqual(restored.Inventory, vm.Inventory) {
+		t.Errorf("Load() slots = %v, inventory = %v", restored.Slots(), restored.Inventory)
+	}
+
+	vm.RemoveProduct(manager, "Cola")
+	if len(vm.Slots()) != 0 {
+		t.Errorf("RemoveProduct() left slots behind: %v", vm.Slots())
+	}
+}
+
+func TestServerSelectSlot(t *testing.T) {
+	srv, vm := newTestServer(t)
+	base := startSession(t, srv)
+	registerOperator(t, vm, manager, RoleManager)
+	vm.AssignSlot(manager, "C3", "Candy", 30)
+
+	doJSON(t, srv, http.MethodPost, base+"/coins", `{"coin": 10}`, nil)
+	status, body := doJSON(t, srv, http.MethodPost, base+"/select", `{"slot": "C3"}`, nil)
+	if status != http.StatusOK || body["slot"] != "C3" || body["product"] != "Candy" {
+		t.Errorf("POST /select by slot = %d %v, want 200 naming Candy", status, body)
+	}
+	if status, _ := doJSON(t, srv, http.MethodPost, base+"/select", `{"slot": "Z9"}`, nil); status != http.StatusNotFound {
+		t.Errorf("POST /select with unknown slot = %d, want 404", status)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version     int                        `json:"version"`
+	Inventory   map[string]Item            `json:"inventory"`
+	Coins       Change                     `json:"coins"`
+	Banknotes   Change                     `json:"banknotes,omitempty"`
+	Sessions    map[string]sessionSnapshot `json:"sessions"`
+	Maintenance bool                       `json:"maintenance,omitempty"`
+	Slots       []Slot                     `json:"slots,omitempty"`
+}
+
+type sessionSnapshot struct {
+	Inserted   Change    `json:"inserted"`
+	Notes      Change    `json:"notes,omitempty"`
+	LastActive time.Time `json:"lastActive"`
+}
+
+func (v *VendingMachine) Save(w io.Writer) error {
+	v.Lock()
+	defer v.Unlock()
+	return v.save(w)
+}
+
+func (v *VendingMachine) save(w io.Writer) error {
+	snap := snapshot{
+		Version:     snapshotVersion,
+		Inventory:   v.Inventory,
+		Coins:       v.Coins,
+		Banknotes:   v.Banknotes,
+		Sessions:    make(map[string]sessionSnapshot, len(v.sessions)),
+		Maintenance: v.maintenance,
+	}
+	for _, slot := range v.slots {
+		snap.Slots = append(snap.Slots, *slot)
+	}
+	sort.Slice(snap.Slots, func(i, j int) bool { return snap.Slots[i].Code < snap.Slots[j].Code })
+	for id, sess := range v.sessions {
+		snap.Sessions[id] = sessionSnapshot{Inserted: sess.inserted, Notes: sess.notes, LastActive: sess.lastActive}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(snap); err != nil {
+		return fmt.Errorf("failed to write the snapshot: %w", err)
+	}
+	return nil
+}
+
+// Load replaces the inventory, slots, coins, pending credit and maintenance
+// flag with those of a snapshot written by Save. Operators, listeners, the
+// event store and the sales history are kept. Listeners see a single change
+// with ActionLoadSnapshot if the state differs. Snapshots leave sales out so
+// that autosaving stays cheap however much the machine sells; Replay the
+// event log to rebuild them.
+func (v *VendingMachine) Load(r io.Reader) error {
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+	if snap.Version < 1 || snap.Version > snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	inventory := make(map[string]Item, len(snap.Inventory))
+	for name, item := range snap.Inventory {
+		if item.Name != name || item.Price < 0 || item.Stock < 0 {
+			return fmt.Errorf("%w: invalid product %q in snapshot", ErrInvalidArgument, name)
+		}
+		inventory[name] = item
+	}
+	// The accepted denominations never change after construction, so they are
+	// read before taking the lock.
+	coins := make(map[int]int, len(snap.Coins))
+	for coin, count := range snap.Coins {
+		if err := checkCash(v.coinValues, ErrInvalidCoin, coin, count); err != nil {
+			return err
+		}
+		coins[coin] = count
+	}
+	notes := make(map[int]int, len(snap.Banknotes))
+	for note, count := range snap.Banknotes {
+		if err := checkCash(v.noteValues, ErrInvalidBanknote, note, count); err != nil {
+			return err
+		}
+		notes[note] = count
+	}
+	slots := make(map[string]*Slot, len(snap.Slots))
+	for _, slot := range snap.Slots {
+		if _, ok := inventory[slot.Product]; !ok || slot.Code == "" || slot.Stock < 0 || slot.Stock > slot.Capacity {
+			return fmt.Errorf("%w: invalid slot %q in snapshot", ErrInvalidArgument, slot.Code)
+		}
+		if _, ok := slots[slot.Code]; ok {
+			return fmt.Errorf("%w: duplicate slot %q in snapshot", ErrInvalidArgument, slot.Code)
+		}
+		slots[slot.Code] = &slot
+	}
+	slotStock := make(map[string]int)
+	for _, slot := range slots {
+		// Disabled slots still count as slots, so their product must then
+		// report the stock of the enabled ones only.
+		stock := slot.Stock
+		if slot.Disabled {
+			stock = 0
+		}
+		slotStock[slot.Product] += stock
+	}
+	for name, stock := range slotStock {
+		if inventory[name].Stock != stock {
+			return fmt.Errorf("%w: product %q has stock %d in snapshot but its slots hold %d", ErrInvalidArgument, name, inventory[name].Stock, stock)
+		}
+	}
+	sessions := map[string]*session{"": newSession(time.Time{})}
+	for id, s := range snap.Sessions {
+		sess := newSession(s.LastActive)
+		for coin, count := range s.Inserted {
+			if err := checkCash(v.coinValues, ErrInvalidCoin, coin, count); err != nil {
+				return err
+			}
+			sess.inserted[coin] = count
+			sess.balance += coin * count
+		}
+		for note, count := range s.Notes {
+			if err := checkCash(v.noteValues, ErrInvalidBanknote, note, count); err != nil {
+				return err
+			}
+			sess.notes[note] = count
+			sess.balance += note * count
+		}
+		sessions[id] = sess
+	}
+
+	v.Lock()
+	defer v.Unlock()
+	from := v.state()
+	v.Inventory = inventory
+	v.Coins = coins
+	v.Banknotes = notes
+	v.sessions = sessions
+	v.maintenance = snap.Maintenance
+	v.slots = slots
+	v.notify(from, ActionLoadSnapshot)
+	return nil
+}
+
+// EnableAutosave writes a snapshot to path now and after every change to the
+// machine's state. Snapshots are written to a temporary file and renamed into
+// place, so a crash never leaves a partial snapshot behind. Later failures do
+// not undo the change that triggered them; they are reported by AutosaveErr.
+func (v *VendingMachine) EnableAutosave(path string) error {
+	v.Lock()
+	defer v.Unlock()
+	v.autosavePath = path
+	v.autosave()
+	return v.autosaveErr
+}
+
+func (v *VendingMachine) AutosaveErr() error {
+	v.Lock()
+	defer v.Unlock()
+	return v.autosaveErr
+}
+
+func (v *VendingMachine) autosave() {
+	if v.autosavePath == "" {
+		return
+	}
+	v.autosaveErr = v.saveFile(v.autosavePath)
+}
+
+func (v *VendingMachine) saveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := v.save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace the snapshot: %w", err)
+	}
+	return nil
+}
+
+func checkCash(accepted map[int]bool, invalid error, value, count int) error {
+	if !accepted[value] {
+		return fmt.Errorf("%w in snapshot: %d", invalid, value)
+	}
+	if count < 0 {
+		return fmt.Errorf("%w: negative count of %d in snapshot", ErrInvalidArgument, value)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{
+		"Cola":  {Name: "Cola", Price: 25, Stock: 5},
+		"Chips": {Name: "Chips", Price: 35, Stock: 10},
+	})
+	vm.SetClock(fixedClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)))
+	loadCoins(t, vm, map[int]int{5: 4, 10: 2})
+	vm.InsertCoin(25)
+	vm.SelectProduct("Cola")
+	vm.InsertCoin(10)
+	sess, _ := vm.StartSession()
+	sess.InsertCoin(25)
+	sess.InsertCoin(10)
+
+	var buf bytes.Buffer
+	if err := vm.Save(&buf); err != nil {
+		t.Fatalf("Save() unexpected error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `"version": 1`) {
+		t.Errorf("Save() = %s, want a version field", buf.String())
+	}
+
+	restored := NewVendingMachine(map[string]Item{})
+	restored.SetClock(fixedClock(time.Date(2025, 1, 1, 12, 0, 30, 0, time.UTC)))
+	var changes []StateChange
+	restored.Subscribe(func(c StateChange) {
+		changes = append(changes, c)
+	})
+	if err := restored.Load(&buf); err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if want := []StateChange{{From: StateIdle, To: StateHasCredit, Action: ActionLoadSnapshot}}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("Load() state changes = %v, want %v", changes, want)
+	}
+	if !reflect.DeepEqual(restored.Inventory, vm.Inventory) {
+		t.Errorf("Load() inventory = %v, want %v", restored.Inventory, vm.Inventory)
+	}
+	if !reflect.DeepEqual(restored.CoinStock(), vm.CoinStock()) {
+		t.Errorf("Load() coins = %v, want %v", restored.CoinStock(), vm.CoinStock())
+	}
+	if restored.Balance() != 10 || restored.State() != StateHasCredit {
+		t.Errorf("Load() balance = %v, state = %v, want 10, %v", restored.Balance(), restored.State(), StateHasCredit)
+	}
+
+	resumed, err := restored.Session(sess.ID)
+	if err != nil {
+		t.Fatalf("Session() unexpected error = %v", err)
+	}
+	if _, _, err := resumed.SelectProduct("Chips"); err != nil {
+		t.Fatalf("SelectProduct() on restored session unexpected error = %v", err)
+	}
+	if resumed.Balance() != 0 || restored.Inventory["Chips"].Stock != 9 {
+		t.Errorf("SelectProduct() balance = %v, stock = %v, want 0, 9", resumed.Balance(), restored.Inventory["Chips"].Stock)
+	}
+}
+
+func TestLoadInvalidSnapshot(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		errContains string
+	}{
+		{"malformed", `{"version": `, "failed to parse snapshot"},
+		{"missing version", `{"inventory": {}}`, "unsupported snapshot version 0"},
+		{"newer version", `{"version": 2}`, "unsupported snapshot version 2"},
+		{"invalid coin", `{"version": 1, "coins": {"3": 1}}`, "not a valid coin"},
+		{"negative coins", `{"version": 1, "coins": {"5": -1}}`, "negative count"},
+		{"mismatched product", `{"version": 1, "inventory": {"Cola": {"name": "Chips"}}}`, "invalid product"},
+		{"duplicate slot", `{"version": 1, "inventory": {"Cola": {"name": "Cola", "stock": 4}},
+			"slots": [{"code": "A1", "product": "Cola", "capacity": 5, "stock": 2}, {"code": "A1", "product": "Cola", "capacity": 5, "stock": 2}]}`, "duplicate slot"},
+		{"stock differs from slots", `{"version": 1, "inventory": {"Cola": {"name": "Cola", "stock": 5}},
+			"slots": [{"code": "A1", "product": "Cola", "capacity": 5, "stock": 2}, {"code": "A2", "product": "Cola", "capacity": 5, "stock": 3, "disabled": true}]}`, "its slots hold 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := NewVendingMachine(map[string]Item{"Cola": {Name: "Cola", Price: 25, Stock: 5}})
+			err := vm.Load(strings.NewReader(tt.data))
+			if err == nil || !contains(err.Error(), tt.errContains) {
+				t.Fatalf("Load() error = %v, want error containing %v", err, tt.errContains)
+			}
+			if vm.Inventory["Cola"].Stock != 5 {
+				t.Errorf("Load() changed the machine despite failing")
+			}
+		})
+	}
+}
+
+func TestLoadIgnoresUnknownFields(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{})
+	data := `{"version": 1, "inventory": {"Cola": {"name": "Cola", "price": 25, "stock": 2, "slot": "A1"}}, "firmware": "2.0"}`
+	if err := vm.Load(strings.NewReader(data)); err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if vm.Inventory["Cola"].Stock != 2 {
+		t.Errorf("Load() stock = %v, want 2", vm.Inventory["Cola"].Stock)
+	}
+}
+
+func TestAutosave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+
+	vm := NewVendingMachine(map[string]Item{"Candy": {Name: "Candy", Price: 10, Stock: 3}})
+	if err := vm.EnableAutosave(path); err != nil {
+		t.Fatalf("EnableAutosave() unexpected error = %v", err)
+	}
+	vm.InsertCoin(10)
+	vm.SelectProduct("Candy")
+	vm.InsertCoin(25)
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("autosave did not write a snapshot: %v", err)
+	}
+	defer file.Close()
+	restored := NewVendingMachine(map[string]Item{})
+	if err := restored.Load(file); err != nil {
+		t.Fatalf("Load() unexpected error = %v", err)
+	}
+	if restored.Inventory["Candy"].Stock != 2 || restored.Balance() != 25 {
+		t.Errorf("autosaved stock = %v, balance = %v, want 2, 25", restored.Inventory["Candy"].Stock, restored.Balance())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("autosave left %d files behind, want only the snapshot", len(entries))
+	}
+}
+
+func TestAutosaveFailure(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{})
+	path := filepath.Join(t.TempDir(), "missing", "state.json")
+	if err := vm.EnableAutosave(path); err == nil {
+		t.Fatalf("EnableAutosave() expected error for a missing directory")
+	}
+	if err := vm.InsertCoin(25); err != nil {
+		t.Errorf("InsertCoin() error = %v, want autosave failures not to refuse it", err)
+	}
+	if vm.Balance() != 25 || vm.AutosaveErr() == nil {
+		t.Errorf("InsertCoin() balance = %v, AutosaveErr() = %v, want 25 and an error", vm.Balance(), vm.AutosaveErr())
+	}
+}
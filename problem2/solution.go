@@ -1,27 +1,66 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"net/http"
+	"os"
 	"sync"
+	"time"
 )
 
 type Item struct {
-	Name  string
-	Price int
-	Stock int
+	Name  string `json:"name"`
+	Price int    `json:"price"`
+	Stock int    `json:"stock"`
 }
 
 type VendingMachine struct {
-	UserBalance int
-	Inventory   map[string]Item
+	Inventory map[string]Item
+	Coins     map[int]int
+	Banknotes map[int]int
+
+	coinValues        map[int]bool
+	noteValues        map[int]bool
+	sessions          map[string]*session
+	sessionTimeout    time.Duration
+	maintenance       bool
+	dispensing        bool
+	dispenser         func([]LineItem)
+	listeners         map[int]func(StateChange)
+	nextListener      int
+	events            EventStore
+	now               func() time.Time
+	operators         map[string]operatorAccount
+	slots             map[string]*Slot
+	rules             []PricingRule
+	sales             []Sale
+	lowStock          func(Item)
+	lowStockThreshold int
+	autosavePath      string
+	autosaveErr       error
 	sync.Mutex
 }
 
-var validCoins = map[int]bool{
-	1:  true,
-	5:  true,
-	10: true,
-	25: true,
+var (
+	ErrInvalidCoin         = errors.New("not a valid coin")
+	ErrProductNotFound     = errors.New("no product found with name")
+	ErrOutOfStock          = errors.New("not in stock")
+	ErrInsufficientBalance = errors.New("insuffcient bal")
+	ErrProductExists       = errors.New("product already exists")
+	ErrInvalidArgument     = errors.New("invalid argument")
+	ErrInvalidBanknote     = errors.New("not a valid banknote")
+)
+
+type Config struct {
+	Coins     []int
+	Banknotes []int
+}
+
+func DefaultConfig() Config {
+	return Config{Coins: []int{1, 5, 10, 25}, Banknotes: []int{100, 500}}
 }
 
 func main() {
@@ -31,6 +70,44 @@ func main() {
 		"Candy": {Name: "Candy", Price: 10, Stock: 20},
 	}
 	vm := NewVendingMachine(inventory)
+	float := map[int]int{1: 20, 5: 10, 10: 10, 25: 4}
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		addr := ":8080"
+		if len(os.Args) > 2 {
+			addr = os.Args[2]
+		}
+		// Without an admin nobody may load cash, so the machine starts with
+		// whatever float the saved state holds.
+		if pin := os.Getenv("VM_ADMIN_PIN"); pin != "" {
+			admin := Credentials{ID: "admin", PIN: pin}
+			if err := vm.RegisterOperator(Credentials{}, admin.ID, admin.PIN, RoleAdmin); err != nil {
+				log.Fatal(err)
+			}
+			if err := vm.LoadCoins(admin, float); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if path := os.Getenv("VM_STATE"); path != "" {
+			if file, err := os.Open(path); err == nil {
+				err = vm.Load(file)
+				file.Close()
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+			if err := vm.EnableAutosave(path); err != nil {
+				log.Fatal(err)
+			}
+		}
+		go vm.RunSessionReaper(context.Background(), 10*time.Second)
+		log.Printf("Vending Machine listening on %s", addr)
+		log.Fatal(http.ListenAndServe(addr, NewServer(vm)))
+	}
+
+	demo := Credentials{ID: "demo", PIN: "0000"}
+	vm.RegisterOperator(Credentials{}, demo.ID, demo.PIN, RoleAdmin)
+	vm.LoadCoins(demo, float)
 
 	fmt.Println("Vending Machine is ready.")
 	fmt.Println("---")
@@ -41,11 +118,11 @@ func main() {
 		fmt.Printf("Error: %v\n", err)
 	}
 	fmt.Println("Selecting 'Candy' (Price 10)...")
-	change, err := vm.SelectProduct("Candy")
+	change, _, err := vm.SelectProduct("Candy")
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Printf("Success! Product dispensed. Change: %d\n", change)
+		fmt.Printf("Success! Product dispensed. Change: %v\n", change)
 	}
 	fmt.Println("---")
 
@@ -55,11 +132,11 @@ func main() {
 		fmt.Printf("Error: %v\n", err)
 	}
 	fmt.Println("Selecting 'Chips' (Price 35)...")
-	change, err = vm.SelectProduct("Chips")
+	change, _, err = vm.SelectProduct("Chips")
 	if err != nil {
 		fmt.Printf("Error (as expected): %v\n", err)
 	} else {
-		fmt.Printf("Success! Product dispensed. Change: %d\n", change)
+		fmt.Printf("Success! Product dispensed. Change: %v\n", change)
 	}
 	fmt.Println("Transaction incomplete. Cancelling...")
 	refund := vm.Cancel()
@@ -73,11 +150,11 @@ func main() {
 	vm.InsertCoin(25)
 	fmt.Println("Total inserted: 50")
 	fmt.Println("Selecting 'Chips' (Price 35)...")
-	change, err = vm.SelectProduct("Chips")
+	change, _, err = vm.SelectProduct("Chips")
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 	} else {
-		fmt.Printf("Success! Product dispensed. Change: %d\n", change)
+		fmt.Printf("Success! Product dispensed. Change: %v\n", change)
 	}
 	fmt.Println("---")
 
@@ -90,7 +167,7 @@ func main() {
 
 	fmt.Println("Attempting to buy one more Cola...")
 	vm.InsertCoin(25)
-	_, err = vm.SelectProduct("Cola")
+	_, _, err = vm.SelectProduct("Cola")
 	if err != nil {
 		fmt.Printf("Error (as expected): %v\n", err)
 	}
@@ -98,46 +175,111 @@ func main() {
 }
 
 func NewVendingMachine(inventory map[string]Item) *VendingMachine {
+	return NewVendingMachineWithConfig(inventory, DefaultConfig())
+}
+
+func NewVendingMachineWithConfig(inventory map[string]Item, cfg Config) *VendingMachine {
 	return &VendingMachine{
-		UserBalance: 0,
-		Inventory:   inventory,
+		Inventory:      inventory,
+		Coins:          make(map[int]int),
+		Banknotes:      make(map[int]int),
+		coinValues:     denominations(cfg.Coins),
+		noteValues:     denominations(cfg.Banknotes),
+		sessions:       map[string]*session{"": newSession(time.Time{})},
+		slots:          make(map[string]*Slot),
+		sessionTimeout: DefaultSessionTimeout,
+		listeners:      make(map[int]func(StateChange)),
+		now:            time.Now,
+		operators:      make(map[string]operatorAccount),
 	}
 }
 
 func (v *VendingMachine) InsertCoin(coinValue int) error {
-	if !validCoins[coinValue] {
-		return fmt.Errorf("not a valid coin")
-	}
-	v.Lock()
-	v.UserBalance += coinValue
-	v.Unlock()
-	return nil
+	return v.insertCash("", EventInsertCoin, coinValue)
+}
+
+func (v *VendingMachine) InsertBanknote(value int) error {
+	return v.insertCash("", EventInsertBanknote, value)
+}
+
+// SelectProduct returns the change together with the pricing rules that
+// discounted the product.
+func (v *VendingMachine) SelectProduct(productName string) (Change, []Discount, error) {
+	return v.selectProduct("", productName)
+}
+
+func (v *VendingMachine) Balance() int {
+	return v.balance("")
 }
 
-func (v *VendingMachine) SelectProduct(productName string) (int, error) {
+func (v *VendingMachine) Cancel() int {
+	return v.cancel("")
+}
+
+func (v *VendingMachine) insertCash(id string, kind EventType, value int) error {
 	v.Lock()
 	defer v.Unlock()
-	prod, ok := v.Inventory[productName]
-	if !ok {
-		return 0, fmt.Errorf("no product found with name : %v", productName)
+	v.expireSessions()
+	e := Event{Type: kind, Session: id, Amount: value}
+	if _, err := v.session(id); err != nil {
+		return v.reject(e, err)
+	}
+	if kind == EventInsertCoin && !v.coinValues[value] {
+		return v.reject(e, ErrInvalidCoin)
+	}
+	if kind == EventInsertBanknote && !v.noteValues[value] {
+		return v.reject(e, ErrInvalidBanknote)
+	}
+	if err := v.checkTransition(ActionInsertCoin); err != nil {
+		return v.reject(e, err)
 	}
-	if prod.Stock == 0 {
-		return 0, fmt.Errorf("%s not in stock", productName)
+	from := v.state()
+	if err := v.commit(e); err != nil {
+		return err
 	}
-	if v.UserBalance < prod.Price {
-		return 0, fmt.Errorf("insuffcient bal : %d", v.UserBalance)
+	v.notify(from, ActionInsertCoin)
+	return nil
+}
+
+func (v *VendingMachine) selectProduct(id, productName string) (Change, []Discount, error) {
+	receipt, err := v.buy(id, map[string]int{productName: 1}, nil, "")
+	if err != nil {
+		return nil, nil, err
 	}
-	prod.Stock--
-	v.Inventory[productName] = prod
-	change := v.UserBalance - prod.Price
-	v.UserBalance = 0
-	return change, nil
+	return receipt.Change, receipt.Discounts, nil
 }
 
-func (v *VendingMachine) Cancel() int {
+func (v *VendingMachine) balance(id string) int {
 	v.Lock()
 	defer v.Unlock()
-	change := v.UserBalance
-	v.UserBalance = 0
-	return change
+	v.expireSessions()
+	if sess, ok := v.sessions[id]; ok {
+		return sess.balance
+	}
+	return 0
+}
+
+func (v *VendingMachine) cancel(id string) int {
+	v.Lock()
+	defer v.Unlock()
+	v.expireSessions()
+	sess, ok := v.sessions[id]
+	if !ok {
+		return 0
+	}
+	from := v.state()
+	e := Event{Type: EventCancel, Session: id, Amount: sess.balance}
+	v.settle(&e)
+	v.notify(from, ActionCancel)
+	return e.Amount
+}
+
+func denominations(values []int) map[int]bool {
+	accepted := make(map[int]bool, len(values))
+	for _, value := range values {
+		if value > 0 {
+			accepted[value] = true
+		}
+	}
+	return accepted
 }
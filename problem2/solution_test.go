@@ -10,8 +10,8 @@ func TestNewVendingMachine(t *testing.T) {
 	}
 	vm := NewVendingMachine(inventory)
 
-	if vm.UserBalance != 0 {
-		t.Errorf("NewVendingMachine() initial balance = %v, want 0", vm.UserBalance)
+	if vm.Balance() != 0 {
+		t.Errorf("NewVendingMachine() initial balance = %v, want 0", vm.Balance())
 	}
 	if len(vm.Inventory) != 1 {
 		t.Errorf("NewVendingMachine() inventory size = %v, want 1", len(vm.Inventory))
@@ -59,8 +59,8 @@ func TestInsertCoin(t *testing.T) {
 				return
 			}
 
-			if vm.UserBalance != tt.coinValue {
-				t.Errorf("InsertCoin() balance = %v, want %v", vm.UserBalance, tt.coinValue)
+			if vm.Balance() != tt.coinValue {
+				t.Errorf("InsertCoin() balance = %v, want %v", vm.Balance(), tt.coinValue)
 			}
 		})
 	}
@@ -79,8 +79,8 @@ func TestInsertMultipleCoins(t *testing.T) {
 		}
 	}
 
-	if vm.UserBalance != expectedTotal {
-		t.Errorf("InsertCoin() total balance = %v, want %v", vm.UserBalance, expectedTotal)
+	if vm.Balance() != expectedTotal {
+		t.Errorf("InsertCoin() total balance = %v, want %v", vm.Balance(), expectedTotal)
 	}
 }
 
@@ -88,6 +88,7 @@ func TestSelectProduct(t *testing.T) {
 	tests := []struct {
 		name         string
 		inventory    map[string]Item
+		coins        map[int]int
 		userBalance  int
 		productName  string
 		wantChange   int
@@ -109,6 +110,7 @@ func TestSelectProduct(t *testing.T) {
 		{
 			name:         "successful purchase with change",
 			inventory:    map[string]Item{"Candy": {Name: "Candy", Price: 10, Stock: 5}},
+			coins:        map[int]int{5: 1},
 			userBalance:  15,
 			productName:  "Candy",
 			wantChange:   5,
@@ -154,9 +156,10 @@ func TestSelectProduct(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			vm := NewVendingMachine(tt.inventory)
-			vm.UserBalance = tt.userBalance
+			loadCoins(t, vm, tt.coins)
+			insertCredit(t, vm, tt.userBalance)
 
-			change, err := vm.SelectProduct(tt.productName)
+			change, _, err := vm.SelectProduct(tt.productName)
 
 			if tt.wantErr {
 				if err == nil {
@@ -166,8 +169,8 @@ func TestSelectProduct(t *testing.T) {
 				if !contains(err.Error(), tt.errContains) {
 					t.Errorf("SelectProduct() error = %v, want error containing %v", err.Error(), tt.errContains)
 				}
-				if vm.UserBalance != tt.finalBalance {
-					t.Errorf("SelectProduct() balance after error = %v, want %v", vm.UserBalance, tt.finalBalance)
+				if vm.Balance() != tt.finalBalance {
+					t.Errorf("SelectProduct() balance after error = %v, want %v", vm.Balance(), tt.finalBalance)
 				}
 				return
 			}
@@ -177,12 +180,12 @@ func TestSelectProduct(t *testing.T) {
 				return
 			}
 
-			if change != tt.wantChange {
-				t.Errorf("SelectProduct() change = %v, want %v", change, tt.wantChange)
+			if change.Total() != tt.wantChange {
+				t.Errorf("SelectProduct() change = %v, want %v", change.Total(), tt.wantChange)
 			}
 
-			if vm.UserBalance != tt.finalBalance {
-				t.Errorf("SelectProduct() final balance = %v, want %v", vm.UserBalance, tt.finalBalance)
+			if vm.Balance() != tt.finalBalance {
+				t.Errorf("SelectProduct() final balance = %v, want %v", vm.Balance(), tt.finalBalance)
 			}
 
 			if item, exists := vm.Inventory[tt.productName]; exists {
@@ -208,7 +211,7 @@ func TestCancel(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			vm := NewVendingMachine(map[string]Item{})
-			vm.UserBalance = tt.userBalance
+			insertCredit(t, vm, tt.userBalance)
 
 			refund := vm.Cancel()
 
@@ -216,8 +219,8 @@ func TestCancel(t *testing.T) {
 				t.Errorf("Cancel() refund = %v, want %v", refund, tt.wantRefund)
 			}
 
-			if vm.UserBalance != 0 {
-				t.Errorf("Cancel() balance after cancel = %v, want 0", vm.UserBalance)
+			if vm.Balance() != 0 {
+				t.Errorf("Cancel() balance after cancel = %v, want 0", vm.Balance())
 			}
 		})
 	}
@@ -230,29 +233,30 @@ func TestCompleteTransactionFlow(t *testing.T) {
 		"Candy": {Name: "Candy", Price: 10, Stock: 20},
 	}
 	vm := NewVendingMachine(inventory)
+	loadCoins(t, vm, map[int]int{5: 1, 10: 1})
 
 	vm.InsertCoin(25)
 	vm.InsertCoin(25)
-	if vm.UserBalance != 50 {
-		t.Errorf("After inserting coins, balance = %v, want 50", vm.UserBalance)
+	if vm.Balance() != 50 {
+		t.Errorf("After inserting coins, balance = %v, want 50", vm.Balance())
 	}
 
-	change, err := vm.SelectProduct("Chips")
+	change, _, err := vm.SelectProduct("Chips")
 	if err != nil {
 		t.Errorf("SelectProduct() unexpected error = %v", err)
 	}
-	if change != 15 {
-		t.Errorf("SelectProduct() change = %v, want 15", change)
+	if change.Total() != 15 {
+		t.Errorf("SelectProduct() change = %v, want 15", change.Total())
 	}
-	if vm.UserBalance != 0 {
-		t.Errorf("After purchase, balance = %v, want 0", vm.UserBalance)
+	if vm.Balance() != 0 {
+		t.Errorf("After purchase, balance = %v, want 0", vm.Balance())
 	}
 	if vm.Inventory["Chips"].Stock != 9 {
 		t.Errorf("After purchase, Chips stock = %v, want 9", vm.Inventory["Chips"].Stock)
 	}
 
 	vm.InsertCoin(25)
-	_, err = vm.SelectProduct("Chips")
+	_, _, err = vm.SelectProduct("Chips")
 	if err == nil {
 		t.Errorf("SelectProduct() expected error for insufficient funds but got none")
 	}
@@ -261,8 +265,8 @@ func TestCompleteTransactionFlow(t *testing.T) {
 	if refund != 25 {
 		t.Errorf("Cancel() refund = %v, want 25", refund)
 	}
-	if vm.UserBalance != 0 {
-		t.Errorf("After cancel, balance = %v, want 0", vm.UserBalance)
+	if vm.Balance() != 0 {
+		t.Errorf("After cancel, balance = %v, want 0", vm.Balance())
 	}
 }
 
@@ -279,3 +283,15 @@ func containsHelper(s, substr string) bool {
 	}
 	return false
 }
+
+func insertCredit(t *testing.T, vm *VendingMachine, amount int) {
+	t.Helper()
+	for _, coin := range []int{25, 10, 5, 1} {
+		for amount >= coin {
+			if err := vm.InsertCoin(coin); err != nil {
+				t.Fatalf("InsertCoin(%d) unexpected error = %v", coin, err)
+			}
+			amount -= coin
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+)
+
+type State int
+
+const (
+	StateIdle State = iota
+	StateHasCredit
+	StateDispensing
+	StateMaintenance
+)
+
+func (s State) String() string {
+	switch s {
+	case StateIdle:
+		return "idle"
+	case StateHasCredit:
+		return "has credit"
+	case StateDispensing:
+		return "dispensing"
+	case StateMaintenance:
+		return "out of service"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+type Action int
+
+const (
+	ActionInsertCoin Action = iota
+	ActionSelect
+	ActionDispensed
+	ActionCancel
+	ActionEnterMaintenance
+	ActionExitMaintenance
+	ActionLoadSnapshot
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionInsertCoin:
+		return "insert coin"
+	case ActionSelect:
+		return "select product"
+	case ActionDispensed:
+		return "finish dispensing"
+	case ActionCancel:
+		return "cancel"
+	case ActionEnterMaintenance:
+		return "enter maintenance"
+	case ActionExitMaintenance:
+		return "exit maintenance"
+	case ActionLoadSnapshot:
+		return "load a snapshot"
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// transitions lists the actions each state accepts. Where an action leads is
+// left to state(), because cancelling one session keeps the machine in
+// StateHasCredit while another session still holds credit. ActionLoadSnapshot
+// is missing because Load replaces the state wholesale rather than stepping
+// from it; it is only reported to listeners.
+var transitions = map[State][]Action{
+	StateIdle:        {ActionInsertCoin, ActionSelect, ActionCancel, ActionEnterMaintenance},
+	StateHasCredit:   {ActionInsertCoin, ActionSelect, ActionCancel},
+	StateDispensing:  {ActionDispensed},
+	StateMaintenance: {ActionCancel, ActionExitMaintenance},
+}
+
+type TransitionError struct {
+	State  State
+	Action Action
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot %v while %v", e.Action, e.State)
+}
+
+type StateChange struct {
+	From   State
+	To     State
+	Action Action
+}
+
+func (v *VendingMachine) State() State {
+	v.Lock()
+	defer v.Unlock()
+	return v.state()
+}
+
+func (v *VendingMachine) state() State {
+	switch {
+	case v.maintenance:
+		return StateMaintenance
+	case v.dispensing:
+		return StateDispensing
+	case v.hasCredit():
+		return StateHasCredit
+	}
+	return StateIdle
+}
+
+func (v *VendingMachine) checkTransition(action Action) error {
+	from := v.state()
+	if !slices.Contains(transitions[from], action) {
+		return &TransitionError{State: from, Action: action}
+	}
+	return nil
+}
+
+// Listeners run while the machine is locked, so they must not call back into it.
+func (v *VendingMachine) Subscribe(fn func(StateChange)) (unsubscribe func()) {
+	v.Lock()
+	defer v.Unlock()
+	id := v.nextListener
+	v.nextListener++
+	v.listeners[id] = fn
+	return func() {
+		v.Lock()
+		defer v.Unlock()
+		delete(v.listeners, id)
+	}
+}
+
+func (v *VendingMachine) notify(from State, action Action) {
+	to := v.state()
+	if to == from {
+		return
+	}
+	change := StateChange{From: from, To: to, Action: action}
+	for id := 0; id < v.nextListener; id++ {
+		if fn, ok := v.listeners[id]; ok {
+			fn(change)
+		}
+	}
+}
+
+// SetDispenser sets the function that drives the hardware for each sale. The
+// machine stays in StateDispensing, refusing coins and selections, until fn
+// returns. fn runs without the machine locked, so it may call back into it.
+func (v *VendingMachine) SetDispenser(fn func(items []LineItem)) {
+	v.Lock()
+	defer v.Unlock()
+	v.dispenser = fn
+}
+
+// dispense takes the machine through StateDispensing for a sale that has been
+// committed. It is called with the machine locked and returns with it locked.
+func (v *VendingMachine) dispense(from State, items []LineItem) {
+	v.dispensing = true
+	v.notify(from, ActionSelect)
+	if v.dispenser != nil {
+		func() {
+			v.Unlock()
+			defer v.Lock()
+			v.dispenser(items)
+		}()
+	}
+	v.dispensing = false
+	v.notify(StateDispensing, ActionDispensed)
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestStateTransitions(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Candy": {Name: "Candy", Price: 10, Stock: 5}})
+	loadCoins(t, vm, map[int]int{5: 5})
+
+	var changes []StateChange
+	vm.Subscribe(func(c StateChange) {
+		changes = append(changes, c)
+	})
+
+	if vm.State() != StateIdle {
+		t.Errorf("State() = %v, want %v", vm.State(), StateIdle)
+	}
+	vm.InsertCoin(10)
+	vm.InsertCoin(5)
+	if vm.State() != StateHasCredit {
+		t.Errorf("State() = %v, want %v", vm.State(), StateHasCredit)
+	}
+	if _, _, err := vm.SelectProduct("Candy"); err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+	vm.InsertCoin(25)
+	vm.Cancel()
+	vm.Cancel()
+
+	want := []StateChange{
+		{From: StateIdle, To: StateHasCredit, Action: ActionInsertCoin},
+		{From: StateHasCredit, To: StateDispensing, Action: ActionSelect},
+		{From: StateDispensing, To: StateIdle, Action: ActionDispensed},
+		{From: StateIdle, To: StateHasCredit, Action: ActionInsertCoin},
+		{From: StateHasCredit, To: StateIdle, Action: ActionCancel},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Subscribe() changes = %v, want %v", changes, want)
+	}
+}
+
+func TestFailedSelectionKeepsState(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Chips": {Name: "Chips", Price: 35, Stock: 10}})
+	var changes []StateChange
+	vm.Subscribe(func(c StateChange) {
+		changes = append(changes, c)
+	})
+
+	vm.InsertCoin(25)
+	if _, _, err := vm.SelectProduct("Chips"); err == nil {
+		t.Fatalf("SelectProduct() expected error but got none")
+	}
+	if vm.State() != StateHasCredit {
+		t.Errorf("State() = %v, want %v", vm.State(), StateHasCredit)
+	}
+	if len(changes) != 1 {
+		t.Errorf("Subscribe() changes = %v, want only the insert", changes)
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Candy": {Name: "Candy", Price: 10, Stock: 5}})
+	registerOperator(t, vm, technician, RoleTechnician)
+
+	if _, err := vm.LockForMaintenance(technician); err != nil {
+		t.Fatalf("LockForMaintenance() unexpected error = %v", err)
+	}
+	if vm.State() != StateMaintenance {
+		t.Errorf("State() = %v, want %v", vm.State(), StateMaintenance)
+	}
+
+	var transitionErr *TransitionError
+	err := vm.InsertCoin(10)
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("InsertCoin() error = %v, want *TransitionError", err)
+	}
+	if transitionErr.State != StateMaintenance || transitionErr.Action != ActionInsertCoin {
+		t.Errorf("InsertCoin() error = %+v, want insert coin while out of service", transitionErr)
+	}
+	if err.Error() != "cannot insert coin while out of service" {
+		t.Errorf("TransitionError.Error() = %v", err)
+	}
+	if _, _, err := vm.SelectProduct("Candy"); !errors.As(err, &transitionErr) {
+		t.Errorf("SelectProduct() error = %v, want *TransitionError", err)
+	}
+	if _, err := vm.LockForMaintenance(technician); !errors.As(err, &transitionErr) {
+		t.Errorf("LockForMaintenance() error = %v, want *TransitionError", err)
+	}
+	if refund := vm.Cancel(); refund != 0 {
+		t.Errorf("Cancel() refund = %v, want 0", refund)
+	}
+
+	if err := vm.EndMaintenance(technician); err != nil {
+		t.Fatalf("EndMaintenance() unexpected error = %v", err)
+	}
+	if err := vm.EndMaintenance(technician); !errors.As(err, &transitionErr) {
+		t.Errorf("EndMaintenance() error = %v, want *TransitionError", err)
+	}
+	if err := vm.InsertCoin(10); err != nil {
+		t.Errorf("InsertCoin() unexpected error after maintenance = %v", err)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{})
+	calls := 0
+	unsubscribe := vm.Subscribe(func(StateChange) {
+		calls++
+	})
+
+	vm.InsertCoin(5)
+	unsubscribe()
+	vm.Cancel()
+
+	if calls != 1 {
+		t.Errorf("Subscribe() listener called %d times, want 1", calls)
+	}
+}
+
+func TestDispensingRefusesActions(t *testing.T) {
+	vm := NewVendingMachine(map[string]Item{"Candy": {Name: "Candy", Price: 10, Stock: 5}})
+	registerOperator(t, vm, technician, RoleTechnician)
+	var changes []StateChange
+	vm.Subscribe(func(c StateChange) {
+		changes = append(changes, c)
+	})
+
+	var dispensed []LineItem
+	var stateDuring State
+	var insertErr, selectErr, lockErr error
+	vm.SetDispenser(func(items []LineItem) {
+		dispensed = items
+		stateDuring = vm.State()
+		insertErr = vm.InsertCoin(10)
+		_, _, selectErr = vm.SelectProduct("Candy")
+		_, lockErr = vm.LockForMaintenance(technician)
+	})
+
+	vm.InsertCoin(10)
+	if _, _, err := vm.SelectProduct("Candy"); err != nil {
+		t.Fatalf("SelectProduct() unexpected error = %v", err)
+	}
+
+	if want := []LineItem{{Product: "Candy", Quantity: 1, UnitPrice: 10}}; !reflect.DeepEqual(dispensed, want) {
+		t.Errorf("dispenser items = %v, want %v", dispensed, want)
+	}
+	if stateDuring != StateDispensing {
+		t.Errorf("State() while dispensing = %v, want %v", stateDuring, StateDispensing)
+	}
+	var transitionErr *TransitionError
+	if !errors.As(insertErr, &transitionErr) || transitionErr.State != StateDispensing || transitionErr.Action != ActionInsertCoin {
+		t.Errorf("InsertCoin() while dispensing error = %v, want insert coin while dispensing", insertErr)
+	}
+	if !errors.As(selectErr, &transitionErr) || transitionErr.Action != ActionSelect {
+		t.Errorf("SelectProduct() while dispensing error = %v, want *TransitionError", selectErr)
+	}
+	if !errors.As(lockErr, &transitionErr) || transitionErr.State != StateDispensing {
+		t.Errorf("LockForMaintenance() while dispensing error = %v, want *TransitionError", lockErr)
+	}
+	if vm.State() != StateIdle || vm.Inventory["Candy"].Stock != 4 {
+		t.Errorf("after dispensing state = %v, stock = %v, want idle with 4 left", vm.State(), vm.Inventory["Candy"].Stock)
+	}
+
+	want := []StateChange{
+		{From: StateIdle, To: StateHasCredit, Action: ActionInsertCoin},
+		{From: StateHasCredit, To: StateDispensing, Action: ActionSelect},
+		{From: StateDispensing, To: StateIdle, Action: ActionDispensed},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Subscribe() changes = %v, want %v", changes, want)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A Backoff computes the delay before retry number n, starting at 1. prev is
+// the delay that was actually waited before the previous retry, after any
+// caps, and is zero before the first retry.
+type Backoff interface {
+	Next(n int, prev time.Duration) time.Duration
+}
+
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+func (b ConstantBackoff) Next(n int, prev time.Duration) time.Duration {
+	return b.Delay
+}
+
+type LinearBackoff struct {
+	Initial time.Duration
+	Step    time.Duration
+}
+
+func (b LinearBackoff) Next(n int, prev time.Duration) time.Duration {
+	return b.Initial + time.Duration(n-1)*b.Step
+}
+
+// ExponentialBackoff multiplies the delay by Multiplier, or 2 when it is not
+// set, after every retry.
+type ExponentialBackoff struct {
+	Initial    time.Duration
+	Multiplier float64
+}
+
+func (b ExponentialBackoff) Next(n int, prev time.Duration) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(n-1))
+	if delay >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+// FullJitter waits a random delay between zero and the one computed by
+// Backoff.
+type FullJitter struct {
+	Backoff Backoff
+	Rand    *Rand
+}
+
+func (b FullJitter) Next(n int, prev time.Duration) time.Duration {
+	return b.Rand.between(0, b.Backoff.Next(n, prev))
+}
+
+// EqualJitter keeps half of the delay computed by Backoff and randomises the
+// other half.
+type EqualJitter struct {
+	Backoff Backoff
+	Rand    *Rand
+}
+
+func (b EqualJitter) Next(n int, prev time.Duration) time.Duration {
+	delay := b.Backoff.Next(n, prev)
+	return delay/2 + b.Rand.between(0, delay-delay/2)
+}
+
+// DecorrelatedJitter waits a random delay between Base and three times the
+// previous delay, so consecutive delays grow without moving in lockstep
+// across clients. Cap it with WithMaxDelay.
+type DecorrelatedJitter struct {
+	Base time.Duration
+	Rand *Rand
+}
+
+func (b DecorrelatedJitter) Next(n int, prev time.Duration) time.Duration {
+	upper := b.Base
+	if prev > math.MaxInt64/3 {
+		upper = math.MaxInt64
+	} else if prev*3 > upper {
+		upper = prev * 3
+	}
+	return b.Rand.between(b.Base, upper)
+}
+
+// Rand is a random source that is safe to share between retries running in
+// parallel. A nil *Rand uses the global source of math/rand.
+type Rand struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func NewRand(seed int64) *Rand {
+	return &Rand{rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (r *Rand) between(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	span := int64(hi - lo)
+	if span == math.MaxInt64 {
+		span--
+	}
+	if r == nil {
+		return lo + time.Duration(rand.Int63n(span+1))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return lo + time.Duration(r.rnd.Int63n(span+1))
+}
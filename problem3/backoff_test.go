@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func delays(b Backoff, n int, maxDelay time.Duration) []time.Duration {
+	var got []time.Duration
+	var prev time.Duration
+	for i := 1; i <= n; i++ {
+		prev = b.Next(i, prev)
+		if maxDelay > 0 {
+			prev = min(prev, maxDelay)
+		}
+		got = append(got, prev)
+	}
+	return got
+}
+
+func TestDeterministicBackoffs(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration
+	}{
+		{"constant", ConstantBackoff{Delay: 10 * ms}, []time.Duration{10 * ms, 10 * ms, 10 * ms, 10 * ms}},
+		{"linear", LinearBackoff{Initial: 10 * ms, Step: 5 * ms}, []time.Duration{10 * ms, 15 * ms, 20 * ms, 25 * ms}},
+		{"exponential", ExponentialBackoff{Initial: 10 * ms}, []time.Duration{10 * ms, 20 * ms, 40 * ms, 80 * ms}},
+		{"exponential with multiplier", ExponentialBackoff{Initial: 10 * ms, Multiplier: 3}, []time.Duration{10 * ms, 30 * ms, 90 * ms, 270 * ms}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := delays(tt.backoff, 4, 0); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffOverflow(t *testing.T) {
+	if got := (ExponentialBackoff{Initial: time.Second}).Next(200, 0); got != math.MaxInt64 {
+		t.Errorf("Next(200) = %v, want it clamped to the largest duration", got)
+	}
+}
+
+func TestJitterBackoffs(t *testing.T) {
+	base := ExponentialBackoff{Initial: 10 * time.Millisecond}
+	tests := []struct {
+		name    string
+		backoff func(r *Rand) Backoff
+		bounds  func(n int, prev time.Duration) (time.Duration, time.Duration)
+	}{
+		{
+			name:    "full jitter",
+			backoff: func(r *Rand) Backoff { return FullJitter{Backoff: base, Rand: r} },
+			bounds: func(n int, prev time.Duration) (time.Duration, time.Duration) {
+				return 0, base.Next(n, prev)
+			},
+		},
+		{
+			name:    "equal jitter",
+			backoff: func(r *Rand) Backoff { return EqualJitter{Backoff: base, Rand: r} },
+			bounds: func(n int, prev time.Duration) (time.Duration, time.Duration) {
+				return base.Next(n, prev) / 2, base.Next(n, prev)
+			},
+		},
+		{
+			name:    "decorrelated jitter",
+			backoff: func(r *Rand) Backoff { return DecorrelatedJitter{Base: 10 * time.Millisecond, Rand: r} },
+			bounds: func(n int, prev time.Duration) (time.Duration, time.Duration) {
+				return 10 * time.Millisecond, max(10*time.Millisecond, 3*prev)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.backoff(NewRand(1))
+			var prev time.Duration
+			for n := 1; n <= 20; n++ {
+				got := b.Next(n, prev)
+				lo, hi := tt.bounds(n, prev)
+				if got < lo || got > hi {
+					t.Errorf("Next(%d) = %v, want within [%v, %v]", n, got, lo, hi)
+				}
+				prev = got
+			}
+
+			first := delays(tt.backoff(NewRand(7)), 10, 0)
+			second := delays(tt.backoff(NewRand(7)), 10, 0)
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("Next() with the same seed = %v and %v, want the same delays", first, second)
+			}
+			if other := delays(tt.backoff(NewRand(8)), 10, 0); reflect.DeepEqual(first, other) {
+				t.Errorf("Next() with different seeds = %v, want different delays", other)
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitterCapped(t *testing.T) {
+	b := DecorrelatedJitter{Base: 10 * time.Millisecond, Rand: NewRand(1)}
+	for _, d := range delays(b, 30, 50*time.Millisecond) {
+		if d > 50*time.Millisecond {
+			t.Errorf("Next() = %v, want at most the 50ms cap", d)
+		}
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		return "", ErrTransient
+	}
+
+	start := time.Now()
+	_, err := Retry(context.Background(), fn,
+		WithRetries(3), WithBackoff(LinearBackoff{Initial: 10 * time.Millisecond, Step: 10 * time.Millisecond}))
+	duration := time.Since(start)
+
+	if !errors.Is(err, ErrTransient) {
+		t.Errorf("Retry() error = %v, want ErrTransient", err)
+	}
+	if callCount != 4 {
+		t.Errorf("Retry() call count = %v, want 4", callCount)
+	}
+	if duration < 60*time.Millisecond || duration > 200*time.Millisecond {
+		t.Errorf("Retry() duration = %v, want about 60ms of linear backoff", duration)
+	}
+}
+
+func TestRetryMaxDelay(t *testing.T) {
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		return "", ErrTransient
+	}
+
+	start := time.Now()
+	Retry(context.Background(), fn, WithRetries(4), WithDelay(10*time.Millisecond), WithMaxDelay(15*time.Millisecond))
+	duration := time.Since(start)
+
+	if duration < 55*time.Millisecond || duration > 150*time.Millisecond {
+		t.Errorf("Retry() duration = %v, want about 10+15+15+15ms with the cap", duration)
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		return "", ErrTransient
+	}
+
+	start := time.Now()
+	_, err := Retry(context.Background(), fn,
+		WithRetries(10), WithBackoff(ConstantBackoff{Delay: 20 * time.Millisecond}), WithMaxElapsed(50*time.Millisecond))
+	duration := time.Since(start)
+
+	if !errors.Is(err, ErrTransient) {
+		t.Errorf("Retry() error = %v, want ErrTransient", err)
+	}
+	if callCount != 3 {
+		t.Errorf("Retry() call count = %v, want 3 (a third wait would pass 50ms)", callCount)
+	}
+	if duration > 50*time.Millisecond {
+		t.Errorf("Retry() duration = %v, want at most 50ms", duration)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// Permanent marks err as not worth retrying. Retry gives up on it even when a
+// RetryIf predicate would accept it, and errors.Is reports it as ErrPermanent.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func (e *permanentError) Is(target error) bool {
+	return target == ErrPermanent
+}
+
+// IsRetryable is the default classification used by Retry. An error is
+// retried if anything in its chain reports Retryable() or Temporary() as
+// true, or if it wraps ErrTransient. Errors that wrap ErrPermanent are never
+// retried.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrPermanent) {
+		return false
+	}
+	var retryable interface{ Retryable() bool }
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	var temporary interface{ Temporary() bool }
+	if errors.As(err, &temporary) {
+		return temporary.Temporary()
+	}
+	return errors.Is(err, ErrTransient)
+}
+
+// RetryIf replaces IsRetryable with pred for deciding which errors to retry.
+// Errors that wrap ErrPermanent still end the retries. A nil pred restores
+// the default.
+func RetryIf(pred func(error) bool) Option {
+	return func(c *retryConfig) {
+		if pred == nil {
+			pred = IsRetryable
+		}
+		c.retryIf = pred
+	}
+}
+
+// retryAfter reports the delay asked for by an error in err's chain that has a
+// RetryAfter method, such as one built from an HTTP 429 or 503 response.
+func retryAfter(err error) (time.Duration, bool) {
+	var hint interface{ RetryAfter() time.Duration }
+	if !errors.As(err, &hint) {
+		return 0, false
+	}
+	return max(hint.RetryAfter(), 0), true
+}
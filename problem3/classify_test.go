@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type temporaryError struct {
+	temporary bool
+}
+
+func (e temporaryError) Error() string   { return "temporary error" }
+func (e temporaryError) Temporary() bool { return e.temporary }
+
+type retryableError struct {
+	retryable bool
+}
+
+func (e retryableError) Error() string   { return "retryable error" }
+func (e retryableError) Retryable() bool { return e.retryable }
+
+type throttledError struct {
+	after time.Duration
+}
+
+func (e throttledError) Error() string             { return "429 too many requests" }
+func (e throttledError) Temporary() bool           { return true }
+func (e throttledError) RetryAfter() time.Duration { return e.after }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"transient", ErrTransient, true},
+		{"wrapped transient", fmt.Errorf("call failed: %w", ErrTransient), true},
+		{"permanent", ErrPermanent, false},
+		{"unknown", errors.New("boom"), false},
+		{"temporary", temporaryError{temporary: true}, true},
+		{"not temporary", temporaryError{temporary: false}, false},
+		{"wrapped temporary", fmt.Errorf("call failed: %w", temporaryError{temporary: true}), true},
+		{"retryable", retryableError{retryable: true}, true},
+		{"not retryable", retryableError{retryable: false}, false},
+		{"marked permanent", Permanent(temporaryError{temporary: true}), false},
+		{"permanent joined with transient", errors.Join(ErrTransient, ErrPermanent), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	cause := errors.New("bad request")
+	err := Permanent(cause)
+
+	if !errors.Is(err, ErrPermanent) {
+		t.Errorf("Permanent() = %v, want it to match ErrPermanent", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("Permanent() = %v, want it to wrap the cause", err)
+	}
+	if err.Error() != cause.Error() {
+		t.Errorf("Permanent().Error() = %q, want %q", err.Error(), cause.Error())
+	}
+	if Permanent(nil) != nil {
+		t.Errorf("Permanent(nil) = %v, want nil", Permanent(nil))
+	}
+}
+
+func TestRetryClassification(t *testing.T) {
+	customErr := errors.New("custom error")
+	tests := []struct {
+		name      string
+		err       error
+		opts      []Option
+		wantCalls int
+	}{
+		{"temporary error is retried", temporaryError{temporary: true}, nil, 3},
+		{"retryable error is retried", retryableError{retryable: true}, nil, 3},
+		{"non-retryable error is not", retryableError{retryable: false}, nil, 1},
+		{"predicate accepts custom error", customErr, []Option{RetryIf(func(err error) bool { return errors.Is(err, customErr) })}, 3},
+		{"predicate rejects transient error", ErrTransient, []Option{RetryIf(func(err error) bool { return false })}, 1},
+		{"permanent overrides predicate", Permanent(customErr), []Option{RetryIf(func(err error) bool { return true })}, 1},
+		{"nil predicate uses default", ErrTransient, []Option{RetryIf(nil)}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callCount := 0
+			fn := func(ctx context.Context) (string, error) {
+				callCount++
+				return "", tt.err
+			}
+
+			opts := append([]Option{WithRetries(2), WithDelay(time.Millisecond)}, tt.opts...)
+			_, err := Retry(context.Background(), fn, opts...)
+
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Retry() error = %v, want %v", err, tt.err)
+			}
+			if callCount != tt.wantCalls {
+				t.Errorf("Retry() call count = %v, want %v", callCount, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryAfterOverridesBackoff(t *testing.T) {
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		if callCount < 3 {
+			return "", throttledError{after: 30 * time.Millisecond}
+		}
+		return "success", nil
+	}
+
+	start := time.Now()
+	result, err := Retry(context.Background(), fn,
+		WithRetries(3), WithBackoff(ConstantBackoff{Delay: time.Second}), WithMaxDelay(time.Millisecond))
+	duration := time.Since(start)
+
+	if err != nil {
+		t.Errorf("Retry() unexpected error = %v", err)
+	}
+	if result != "success" {
+		t.Errorf("Retry() result = %v, want success", result)
+	}
+	if duration < 60*time.Millisecond || duration > 500*time.Millisecond {
+		t.Errorf("Retry() duration = %v, want about 60ms from the Retry-After hints", duration)
+	}
+}
+
+func TestRetryAfterRespectsDeadline(t *testing.T) {
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		return "", throttledError{after: time.Second}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := Retry(ctx, fn, WithRetries(3), WithDelay(time.Millisecond))
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Retry() error = %v, want context.DeadlineExceeded", err)
+	}
+	if callCount != 1 {
+		t.Errorf("Retry() call count = %v, want 1", callCount)
+	}
+	if time.Since(start) > 40*time.Millisecond {
+		t.Errorf("Retry() waited %v, want it to give up without waiting", time.Since(start))
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetryError is returned when Retry runs out of retries or of the time given
+// by WithMaxElapsed. errors.Is and errors.As look through the error of every
+// attempt, in the order they were made.
+type RetryError struct {
+	Errors  []error
+	Elapsed time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts in %v: %v", len(e.Errors), e.Elapsed, e.Last())
+}
+
+func (e *RetryError) Unwrap() []error {
+	return e.Errors
+}
+
+func (e *RetryError) Last() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e.Errors[len(e.Errors)-1]
+}
+
+// OnRetry calls fn after a failed attempt, before waiting delay for the next
+// one.
+func OnRetry(fn func(attempt int, err error, delay time.Duration)) Option {
+	return func(c *retryConfig) {
+		c.onRetry = fn
+	}
+}
+
+// OnGiveUp calls fn once when Retry stops with an error. delay is the wait
+// that was planned before the next attempt, or zero if none was.
+func OnGiveUp(fn func(attempt int, err error, delay time.Duration)) Option {
+	return func(c *retryConfig) {
+		c.onGiveUp = fn
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type hookCall struct {
+	attempt int
+	err     error
+	delay   time.Duration
+}
+
+func TestRetryHooks(t *testing.T) {
+	errs := []error{ErrTransient, temporaryError{temporary: true}, ErrTransient}
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		return "", errs[callCount-1]
+	}
+
+	var retries, giveUps []hookCall
+	Retry(context.Background(), fn,
+		WithRetries(2), WithBackoff(LinearBackoff{Initial: time.Millisecond, Step: time.Millisecond}),
+		OnRetry(func(attempt int, err error, delay time.Duration) {
+			retries = append(retries, hookCall{attempt, err, delay})
+		}),
+		OnGiveUp(func(attempt int, err error, delay time.Duration) {
+			giveUps = append(giveUps, hookCall{attempt, err, delay})
+		}))
+
+	wantRetries := []hookCall{{1, ErrTransient, time.Millisecond}, {2, temporaryError{temporary: true}, 2 * time.Millisecond}}
+	if !reflect.DeepEqual(retries, wantRetries) {
+		t.Errorf("OnRetry() calls = %v, want %v", retries, wantRetries)
+	}
+	wantGiveUps := []hookCall{{3, ErrTransient, 0}}
+	if !reflect.DeepEqual(giveUps, wantGiveUps) {
+		t.Errorf("OnGiveUp() calls = %v, want %v", giveUps, wantGiveUps)
+	}
+}
+
+func TestRetryHooksNotCalledOnSuccess(t *testing.T) {
+	gaveUp := false
+	fn := func(ctx context.Context) (string, error) {
+		return "success", nil
+	}
+
+	Retry(context.Background(), fn, OnGiveUp(func(attempt int, err error, delay time.Duration) {
+		gaveUp = true
+	}))
+
+	if gaveUp {
+		t.Errorf("OnGiveUp() called after a successful attempt")
+	}
+}
+
+func TestRetryGiveUpReasons(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		opts        []Option
+		wantAttempt int
+		wantDelay   time.Duration
+	}{
+		{"permanent error", ErrPermanent, nil, 1, 0},
+		{"retries exhausted", ErrTransient, nil, 2, 0},
+		{"max elapsed", ErrTransient, []Option{WithMaxElapsed(5 * time.Millisecond), WithDelay(time.Second)}, 1, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := func(ctx context.Context) (string, error) {
+				return "", tt.err
+			}
+
+			var got *hookCall
+			opts := append([]Option{WithRetries(1), WithDelay(time.Millisecond)}, tt.opts...)
+			opts = append(opts, OnGiveUp(func(attempt int, err error, delay time.Duration) {
+				got = &hookCall{attempt, err, delay}
+			}))
+			Retry(context.Background(), fn, opts...)
+
+			want := &hookCall{tt.wantAttempt, tt.err, tt.wantDelay}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("OnGiveUp() call = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRetryErrorHoldsEveryAttempt(t *testing.T) {
+	errs := []error{ErrTransient, retryableError{retryable: true}, temporaryError{temporary: true}}
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		return "", errs[callCount-1]
+	}
+
+	start := time.Now()
+	_, err := Retry(context.Background(), fn, WithRetries(2), WithDelay(5*time.Millisecond))
+	duration := time.Since(start)
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("Retry() error = %v, want *RetryError", err)
+	}
+	if !reflect.DeepEqual(retryErr.Errors, errs) {
+		t.Errorf("RetryError.Errors = %v, want %v", retryErr.Errors, errs)
+	}
+	if retryErr.Last() != errs[2] {
+		t.Errorf("RetryError.Last() = %v, want %v", retryErr.Last(), errs[2])
+	}
+	if retryErr.Elapsed < 15*time.Millisecond || retryErr.Elapsed > duration {
+		t.Errorf("RetryError.Elapsed = %v, want between 15ms and %v", retryErr.Elapsed, duration)
+	}
+	if !errors.Is(err, ErrTransient) {
+		t.Errorf("Retry() error = %v, want it to match ErrTransient from the first attempt", err)
+	}
+	var retryable retryableError
+	if !errors.As(err, &retryable) {
+		t.Errorf("Retry() error = %v, want errors.As to find the second attempt's error", err)
+	}
+	if !strings.Contains(err.Error(), "3 attempts") {
+		t.Errorf("Retry() error = %q, want it to mention 3 attempts", err.Error())
+	}
+}
+
+func TestRetryErrorOnMaxElapsed(t *testing.T) {
+	fn := func(ctx context.Context) (string, error) {
+		return "", ErrTransient
+	}
+
+	_, err := Retry(context.Background(), fn,
+		WithRetries(10), WithBackoff(ConstantBackoff{Delay: 20 * time.Millisecond}), WithMaxElapsed(50*time.Millisecond))
+
+	var retryErr *RetryError
+	if !errors.As(err, &retryErr) {
+		t.Fatalf("Retry() error = %v, want *RetryError", err)
+	}
+	if len(retryErr.Errors) != 3 {
+		t.Errorf("RetryError.Errors = %v, want 3 errors", retryErr.Errors)
+	}
+}
+
+func TestRetryPermanentErrorNotWrapped(t *testing.T) {
+	fn := func(ctx context.Context) (string, error) {
+		return "", ErrPermanent
+	}
+
+	_, err := Retry(context.Background(), fn)
+
+	if err != ErrPermanent {
+		t.Errorf("Retry() error = %v, want ErrPermanent unwrapped", err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -11,7 +12,11 @@ var ErrTransient = errors.New("a transient error occurred")
 var ErrPermanent = errors.New("a permanent error occurred")
 
 func main() {
-	fmt.Println(Retry(UnreliableAPICall, 4, time.Duration(100)))
+	fmt.Println(Retry(context.Background(), func(ctx context.Context) (string, error) {
+		return UnreliableAPICall()
+	}, WithRetries(4), WithDelay(time.Duration(100)), OnRetry(func(attempt int, err error, delay time.Duration) {
+		fmt.Printf("Attempt %d failed, retrying after %v delay...\n", attempt, delay)
+	})))
 }
 
 func UnreliableAPICall() (string, error) {
@@ -29,13 +34,122 @@ func UnreliableAPICall() (string, error) {
 	return "Success!", nil
 }
 
-func Retry(fn func() (string, error), retries int, delay time.Duration) (string, error) {
-	resp, err := fn()
-	fmt.Println(retries)
-	if err != nil && retries > 0 && errors.Is(err, ErrTransient) {
-		fmt.Printf("Retrying after %v delay...\n", delay)
-		time.Sleep(delay)
-		return Retry(fn, retries-1, delay*2)
+type Option func(*retryConfig)
+
+type retryConfig struct {
+	retries    int
+	backoff    Backoff
+	maxDelay   time.Duration
+	maxElapsed time.Duration
+	retryIf    func(error) bool
+	onRetry    func(attempt int, err error, delay time.Duration)
+	onGiveUp   func(attempt int, err error, delay time.Duration)
+}
+
+func WithRetries(n int) Option {
+	return func(c *retryConfig) {
+		c.retries = max(n, 0)
+	}
+}
+
+// WithDelay doubles the delay after every retry, starting at d.
+func WithDelay(d time.Duration) Option {
+	return func(c *retryConfig) {
+		c.backoff = ExponentialBackoff{Initial: d}
+	}
+}
+
+func WithBackoff(b Backoff) Option {
+	return func(c *retryConfig) {
+		c.backoff = b
+	}
+}
+
+func WithMaxDelay(d time.Duration) Option {
+	return func(c *retryConfig) {
+		c.maxDelay = d
+	}
+}
+
+// WithMaxElapsed gives up instead of waiting for a retry that would start more
+// than d after the first attempt.
+func WithMaxElapsed(d time.Duration) Option {
+	return func(c *retryConfig) {
+		c.maxElapsed = d
+	}
+}
+
+// Retry calls fn until it succeeds, fails with an error that is not
+// retryable, or runs out of retries, waiting between attempts as the backoff
+// says unless the error asks for a delay with a RetryAfter method. Running out
+// of retries or of the WithMaxElapsed budget returns a *RetryError holding
+// every attempt's error. When ctx is cancelled, or its deadline would pass
+// before the next attempt, it gives up with an error that wraps both the
+// context error and the last attempt's error.
+func Retry[T any](ctx context.Context, fn func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	cfg := retryConfig{
+		retries: 3,
+		backoff: ExponentialBackoff{Initial: 100 * time.Millisecond},
+		retryIf: IsRetryable,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var zero T
+	var errs []error
+	var delay time.Duration
+	start := time.Now()
+	giveUp := func(attempt int, err error, next time.Duration) (T, error) {
+		if cfg.onGiveUp != nil {
+			cfg.onGiveUp(attempt, errs[len(errs)-1], next)
+		}
+		return zero, err
+	}
+	exhausted := func() error {
+		return &RetryError{Errors: errs, Elapsed: time.Since(start)}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := fn(ctx)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, err)
+		if errors.Is(err, ErrPermanent) || !cfg.retryIf(err) {
+			return giveUp(attempt, err, 0)
+		}
+		if attempt > cfg.retries {
+			return giveUp(attempt, exhausted(), 0)
+		}
+		if ctx.Err() != nil {
+			return giveUp(attempt, errors.Join(ctx.Err(), err), 0)
+		}
+
+		if hint, ok := retryAfter(err); ok {
+			delay = hint
+		} else {
+			delay = cfg.backoff.Next(attempt, delay)
+			if cfg.maxDelay > 0 {
+				delay = min(delay, cfg.maxDelay)
+			}
+		}
+		if cfg.maxElapsed > 0 && time.Since(start)+delay > cfg.maxElapsed {
+			return giveUp(attempt, exhausted(), delay)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return giveUp(attempt, errors.Join(context.DeadlineExceeded, err), delay)
+		}
+
+		if cfg.onRetry != nil {
+			cfg.onRetry(attempt, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return giveUp(attempt, errors.Join(ctx.Err(), err), delay)
+		case <-timer.C:
+		}
 	}
-	return resp, err
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -8,12 +9,12 @@ import (
 
 func TestRetrySuccess(t *testing.T) {
 	callCount := 0
-	successFn := func() (string, error) {
+	successFn := func(ctx context.Context) (string, error) {
 		callCount++
 		return "success", nil
 	}
 
-	result, err := Retry(successFn, 3, 10*time.Millisecond)
+	result, err := Retry(context.Background(), successFn, WithRetries(3), WithDelay(10*time.Millisecond))
 
 	if err != nil {
 		t.Errorf("Retry() unexpected error = %v", err)
@@ -28,12 +29,12 @@ func TestRetrySuccess(t *testing.T) {
 
 func TestRetryPermanentError(t *testing.T) {
 	callCount := 0
-	permanentErrorFn := func() (string, error) {
+	permanentErrorFn := func(ctx context.Context) (string, error) {
 		callCount++
 		return "", ErrPermanent
 	}
 
-	result, err := Retry(permanentErrorFn, 3, 10*time.Millisecond)
+	result, err := Retry(context.Background(), permanentErrorFn, WithRetries(3), WithDelay(10*time.Millisecond))
 
 	if !errors.Is(err, ErrPermanent) {
 		t.Errorf("Retry() error = %v, want ErrPermanent", err)
@@ -48,7 +49,7 @@ func TestRetryPermanentError(t *testing.T) {
 
 func TestRetryTransientErrorEventualSuccess(t *testing.T) {
 	callCount := 0
-	eventualSuccessFn := func() (string, error) {
+	eventualSuccessFn := func(ctx context.Context) (string, error) {
 		callCount++
 		if callCount < 3 {
 			return "", ErrTransient
@@ -57,7 +58,7 @@ func TestRetryTransientErrorEventualSuccess(t *testing.T) {
 	}
 
 	start := time.Now()
-	result, err := Retry(eventualSuccessFn, 5, 10*time.Millisecond)
+	result, err := Retry(context.Background(), eventualSuccessFn, WithRetries(5), WithDelay(10*time.Millisecond))
 	duration := time.Since(start)
 
 	if err != nil {
@@ -78,13 +79,13 @@ func TestRetryTransientErrorEventualSuccess(t *testing.T) {
 
 func TestRetryExhaustsAllRetries(t *testing.T) {
 	callCount := 0
-	alwaysTransientFn := func() (string, error) {
+	alwaysTransientFn := func(ctx context.Context) (string, error) {
 		callCount++
 		return "", ErrTransient
 	}
 
 	start := time.Now()
-	result, err := Retry(alwaysTransientFn, 3, 5*time.Millisecond)
+	result, err := Retry(context.Background(), alwaysTransientFn, WithRetries(3), WithDelay(5*time.Millisecond))
 	duration := time.Since(start)
 
 	if !errors.Is(err, ErrTransient) {
@@ -105,12 +106,12 @@ func TestRetryExhaustsAllRetries(t *testing.T) {
 
 func TestRetryZeroRetries(t *testing.T) {
 	callCount := 0
-	transientErrorFn := func() (string, error) {
+	transientErrorFn := func(ctx context.Context) (string, error) {
 		callCount++
 		return "", ErrTransient
 	}
 
-	result, err := Retry(transientErrorFn, 0, 10*time.Millisecond)
+	result, err := Retry(context.Background(), transientErrorFn, WithRetries(0), WithDelay(10*time.Millisecond))
 
 	if !errors.Is(err, ErrTransient) {
 		t.Errorf("Retry() error = %v, want ErrTransient", err)
@@ -126,13 +127,13 @@ func TestRetryZeroRetries(t *testing.T) {
 func TestRetryExponentialBackoff(t *testing.T) {
 	callCount := 0
 
-	transientErrorFn := func() (string, error) {
+	transientErrorFn := func(ctx context.Context) (string, error) {
 		callCount++
 		return "", ErrTransient
 	}
 
 	start := time.Now()
-	Retry(transientErrorFn, 3, 10*time.Millisecond)
+	Retry(context.Background(), transientErrorFn, WithRetries(3), WithDelay(10*time.Millisecond))
 	duration := time.Since(start)
 
 	expectedMinDuration := 70 * time.Millisecond
@@ -153,12 +154,12 @@ func TestRetryExponentialBackoff(t *testing.T) {
 func TestRetryCustomError(t *testing.T) {
 	customErr := errors.New("custom error")
 	callCount := 0
-	customErrorFn := func() (string, error) {
+	customErrorFn := func(ctx context.Context) (string, error) {
 		callCount++
 		return "", customErr
 	}
 
-	result, err := Retry(customErrorFn, 3, 10*time.Millisecond)
+	result, err := Retry(context.Background(), customErrorFn, WithRetries(3), WithDelay(10*time.Millisecond))
 
 	if !errors.Is(err, customErr) {
 		t.Errorf("Retry() error = %v, want custom error", err)
@@ -173,12 +174,12 @@ func TestRetryCustomError(t *testing.T) {
 
 func TestRetryNegativeRetries(t *testing.T) {
 	callCount := 0
-	transientErrorFn := func() (string, error) {
+	transientErrorFn := func(ctx context.Context) (string, error) {
 		callCount++
 		return "", ErrTransient
 	}
 
-	result, err := Retry(transientErrorFn, -1, 10*time.Millisecond)
+	result, err := Retry(context.Background(), transientErrorFn, WithRetries(-1), WithDelay(10*time.Millisecond))
 
 	if !errors.Is(err, ErrTransient) {
 		t.Errorf("Retry() error = %v, want ErrTransient", err)
@@ -190,3 +191,110 @@ func TestRetryNegativeRetries(t *testing.T) {
 		t.Errorf("Retry() call count = %v, want 1 (negative retries treated as zero)", callCount)
 	}
 }
+
+func TestRetryGeneric(t *testing.T) {
+	callCount := 0
+	fn := func(ctx context.Context) (int, error) {
+		callCount++
+		if callCount < 2 {
+			return 0, ErrTransient
+		}
+		return 42, nil
+	}
+
+	result, err := Retry(context.Background(), fn, WithRetries(3), WithDelay(time.Millisecond))
+
+	if err != nil {
+		t.Errorf("Retry() unexpected error = %v", err)
+	}
+	if result != 42 {
+		t.Errorf("Retry() result = %v, want 42", result)
+	}
+}
+
+func TestRetryContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		if callCount == 2 {
+			cancel()
+		}
+		return "", ErrTransient
+	}
+
+	start := time.Now()
+	_, err := Retry(ctx, fn, WithRetries(10), WithDelay(10*time.Millisecond))
+	duration := time.Since(start)
+
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrTransient) {
+		t.Errorf("Retry() error = %v, want both context.Canceled and ErrTransient", err)
+	}
+	if callCount != 2 {
+		t.Errorf("Retry() call count = %v, want 2 (no attempts after cancel)", callCount)
+	}
+	if duration > 100*time.Millisecond {
+		t.Errorf("Retry() duration = %v, want it to stop right after cancel", duration)
+	}
+}
+
+func TestRetryCancelledDuringWait(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		return "", ErrTransient
+	}
+
+	start := time.Now()
+	_, err := Retry(ctx, fn, WithRetries(3), WithDelay(time.Second))
+	duration := time.Since(start)
+
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrTransient) {
+		t.Errorf("Retry() error = %v, want both context.Canceled and ErrTransient", err)
+	}
+	if callCount != 1 {
+		t.Errorf("Retry() call count = %v, want 1", callCount)
+	}
+	if duration > 500*time.Millisecond {
+		t.Errorf("Retry() duration = %v, want the wait interrupted by cancel", duration)
+	}
+}
+
+func TestRetryDeadlineWouldBeExceeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	callCount := 0
+	fn := func(ctx context.Context) (string, error) {
+		callCount++
+		return "", ErrTransient
+	}
+
+	start := time.Now()
+	_, err := Retry(ctx, fn, WithRetries(5), WithDelay(20*time.Millisecond))
+	duration := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, ErrTransient) {
+		t.Errorf("Retry() error = %v, want both context.DeadlineExceeded and ErrTransient", err)
+	}
+	if callCount != 2 {
+		t.Errorf("Retry() call count = %v, want 2 (the 40ms wait would pass the deadline)", callCount)
+	}
+	if duration >= 50*time.Millisecond {
+		t.Errorf("Retry() duration = %v, want it to give up before the deadline", duration)
+	}
+}
+
+func TestRetryPassesContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	var got any
+	Retry(ctx, func(ctx context.Context) (string, error) {
+		got = ctx.Value(key{})
+		return "", nil
+	})
+	if got != "value" {
+		t.Errorf("Retry() passed context value %v, want value", got)
+	}
+}